@@ -1,7 +1,23 @@
 package alterx
 
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
 // Nth Order ClusterBomb with variable length array/values
 func ClusterBomb(payloads *IndexMap, callback func(varMap map[string]interface{}), Vector []string) {
+	clusterBombUntil(payloads, func(varMap map[string]interface{}) bool {
+		callback(varMap)
+		return true
+	}, Vector)
+}
+
+// clusterBombUntil is same as ClusterBomb but stops as soon as callback returns false
+// it returns false if it was stopped before enumerating all values
+func clusterBombUntil(payloads *IndexMap, callback func(varMap map[string]interface{}) bool, Vector []string) bool {
 	// The Goal of implementation is to reduce number of arbitary values by constructing a vector
 
 	// Algorithm
@@ -29,9 +45,11 @@ func ClusterBomb(payloads *IndexMap, callback func(varMap map[string]interface{}
 		index := len(Vector)
 		for _, elem := range payloads.GetNth(index) {
 			vectorMap[payloads.KeyAtNth(index)] = elem
-			callback(vectorMap)
+			if !callback(vectorMap) {
+				return false
+			}
 		}
-		return
+		return true
 	}
 
 	// step 5) if vector is not filled until payload.Cap()-1
@@ -44,8 +62,111 @@ func ClusterBomb(payloads *IndexMap, callback func(varMap map[string]interface{}
 			tmp = append(tmp, Vector...)
 		}
 		tmp = append(tmp, v)
-		ClusterBomb(payloads, callback, tmp) // Recursion
+		if !clusterBombUntil(payloads, callback, tmp) { // Recursion
+			return false
+		}
+	}
+	return true
+}
+
+// payloadIterator iterates over payload combinations of clusterbomb attack
+type payloadIterator interface {
+	// Next returns next payload combination and false if all combinations are exhausted
+	Next() (map[string]interface{}, bool)
+}
+
+// clusterBombIterator iterates over all payload combinations of clusterbomb attack
+// one at a time (pull based) instead of enumerating them recursively
+type clusterBombIterator struct {
+	payloads *IndexMap
+	indexes  []int // index of current value of each variable
+	done     bool
+}
+
+// newClusterBombIterator returns iterator over all payload combinations of given IndexMap
+func newClusterBombIterator(payloads *IndexMap) *clusterBombIterator {
+	it := &clusterBombIterator{
+		payloads: payloads,
+		indexes:  make([]int, payloads.Cap()),
+	}
+	for i := 0; i < payloads.Cap(); i++ {
+		if len(payloads.GetNth(i)) == 0 {
+			// no combinations possible
+			it.done = true
+		}
 	}
+	return it
+}
+
+// Next returns next payload combination and false if all combinations are exhausted
+func (it *clusterBombIterator) Next() (map[string]interface{}, bool) {
+	if it.done {
+		return nil, false
+	}
+	varMap := make(map[string]interface{}, len(it.indexes))
+	for n, index := range it.indexes {
+		varMap[it.payloads.KeyAtNth(n)] = it.payloads.GetNth(n)[index]
+	}
+	// increment indexes like an odometer starting from last variable
+	for n := len(it.indexes) - 1; n >= 0; n-- {
+		it.indexes[n]++
+		if it.indexes[n] < len(it.payloads.GetNth(n)) {
+			return varMap, true
+		}
+		it.indexes[n] = 0
+	}
+	it.done = true
+	return varMap, true
+}
+
+// clusterBombSampler draws random distinct payload combinations of clusterbomb attack
+// without enumerating/materializing all possible combinations
+type clusterBombSampler struct {
+	payloads  *IndexMap
+	remaining int
+	rng       *rand.Rand
+	seen      map[string]struct{}
+}
+
+// newClusterBombSampler returns iterator over size random distinct payload combinations
+// if total combinations are less than or equal to size all combinations are returned
+func newClusterBombSampler(payloads *IndexMap, size int, rng *rand.Rand) payloadIterator {
+	if payloads.Combinations() <= int64(size) {
+		return newClusterBombIterator(payloads)
+	}
+	return &clusterBombSampler{
+		payloads:  payloads,
+		remaining: size,
+		rng:       rng,
+		seen:      map[string]struct{}{},
+	}
+}
+
+// Next returns next random payload combination and false if sample size is exhausted
+func (s *clusterBombSampler) Next() (map[string]interface{}, bool) {
+	if s.remaining <= 0 {
+		return nil, false
+	}
+	indexes := make([]int, s.payloads.Cap())
+	var key []byte
+	for {
+		key = key[:0]
+		for n := range indexes {
+			indexes[n] = s.rng.Intn(len(s.payloads.GetNth(n)))
+			key = strconv.AppendInt(key, int64(indexes[n]), 10)
+			key = append(key, ',')
+		}
+		if _, ok := s.seen[string(key)]; !ok {
+			s.seen[string(key)] = struct{}{}
+			break
+		}
+	}
+	s.remaining--
+	varMap := make(map[string]interface{}, len(indexes))
+	for n, index := range indexes {
+		varMap[s.payloads.KeyAtNth(n)] = s.payloads.GetNth(n)[index]
+	}
+	return varMap, true
 }
 
 type IndexMap struct {
@@ -61,6 +182,21 @@ func (o *IndexMap) Cap() int {
 	return len(o.values)
 }
 
+// Combinations returns total number of combinations of all values (saturated at math.MaxInt64)
+func (o *IndexMap) Combinations() int64 {
+	total := int64(1)
+	for _, v := range o.values {
+		if len(v) == 0 {
+			return 0
+		}
+		if total > math.MaxInt64/int64(len(v)) {
+			return math.MaxInt64
+		}
+		total *= int64(len(v))
+	}
+	return total
+}
+
 // KeyAtNth returns key present at Nth position
 func (o *IndexMap) KeyAtNth(n int) string {
 	return o.indexes[n]
@@ -71,12 +207,57 @@ func NewIndexMap(values map[string][]string) *IndexMap {
 	i := &IndexMap{
 		values: values,
 	}
-	indexes := map[int]string{}
-	counter := 0
+	// keys are sorted so that index of a key is same across runs
+	keys := make([]string, 0, len(values))
 	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	indexes := map[int]string{}
+	for counter, k := range keys {
 		indexes[counter] = k
-		counter++
 	}
 	i.indexes = indexes
 	return i
 }
+
+// shuffleBuffer buffers results and sends them to callback in random order.
+// if window is set only window no of results are buffered and a random buffered
+// result is sent every time a new result is added to full buffer
+type shuffleBuffer struct {
+	window   int // max no of buffered results (0 = unbounded)
+	rng      *rand.Rand
+	buffer   []Result
+	callback func(res Result) error
+}
+
+// newShuffleBuffer returns shuffleBuffer with given window size
+func newShuffleBuffer(window int, rng *rand.Rand, callback func(res Result) error) *shuffleBuffer {
+	return &shuffleBuffer{window: window, rng: rng, callback: callback}
+}
+
+// add buffers result and sends a random buffered result to callback if buffer is full
+func (s *shuffleBuffer) add(res Result) error {
+	if s.window <= 0 || len(s.buffer) < s.window {
+		s.buffer = append(s.buffer, res)
+		return nil
+	}
+	index := s.rng.Intn(len(s.buffer))
+	next := s.buffer[index]
+	s.buffer[index] = res
+	return s.callback(next)
+}
+
+// flush shuffles and sends all buffered results to callback
+func (s *shuffleBuffer) flush() error {
+	s.rng.Shuffle(len(s.buffer), func(i, j int) {
+		s.buffer[i], s.buffer[j] = s.buffer[j], s.buffer[i]
+	})
+	for _, res := range s.buffer {
+		if err := s.callback(res); err != nil {
+			return err
+		}
+	}
+	s.buffer = nil
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -14,19 +15,28 @@ func main() {
 	cliOpts := runner.ParseFlags()
 
 	alterOpts := alterx.Options{
-		Domains:  cliOpts.Domains,
-		Patterns: cliOpts.Patterns,
-		Payloads: cliOpts.Payloads,
-		Limit:    cliOpts.Limit,
-		Enrich:   cliOpts.Enrich, // enrich payloads
-		MaxSize: cliOpts.MaxSize,
+		Domains:      cliOpts.Domains,
+		Patterns:     cliOpts.Patterns,
+		Payloads:     cliOpts.Payloads,
+		DefaultRoot:  cliOpts.DefaultRoot,
+		Limit:        cliOpts.Limit,
+		Enrich:       cliOpts.Enrich, // enrich payloads
+		Typosquat:    cliOpts.Typosquat,
+		TypoGen:      cliOpts.TypoGen,
+		TLDSwap:      cliOpts.TLDSwap,
+		MaxSize:      cliOpts.MaxSize,
+		OutputPrefix: cliOpts.OutputPrefix,
+		OutputSuffix: cliOpts.OutputSuffix,
+	}
+	if cliOpts.CRLF {
+		alterOpts.LineEnding = "\r\n"
 	}
 
-	if cliOpts.PermutationConfig != "" {
-		// read config
-		config, err := alterx.NewConfig(cliOpts.PermutationConfig)
+	if len(cliOpts.PermutationConfig) > 0 {
+		// read and merge configs
+		config, err := alterx.NewConfigFromFiles(cliOpts.PermutationConfig...)
 		if err != nil {
-			gologger.Fatal().Msgf("failed to read %v file got: %v", cliOpts.PermutationConfig, err)
+			gologger.Fatal().Msgf("failed to read permutation config got: %v", err)
 		}
 		if len(config.Patterns) > 0 {
 			alterOpts.Patterns = config.Patterns
@@ -39,12 +49,12 @@ func main() {
 	// configure output writer
 	var output io.Writer
 	if cliOpts.Output != "" {
-		fs, err := os.OpenFile(cliOpts.Output, os.O_CREATE|os.O_WRONLY, 0644)
+		fs, err := runner.OpenOutput(cliOpts.Output)
 		if err != nil {
 			gologger.Fatal().Msgf("failed to open output file %v got %v", cliOpts.Output, err)
 		}
 		output = fs
-		defer fs.Close()
+		defer closeOutput(fs)
 	} else {
 		output = os.Stdout
 	}
@@ -60,8 +70,22 @@ func main() {
 		return
 	}
 
+	if cliOpts.JSONL {
+		if _, err = m.RunStructured(context.Background(), output, runner.FormatJSONL); err != nil {
+			gologger.Error().Msgf("failed to write output to file got %v", err)
+		}
+		return
+	}
+
 	if err = m.ExecuteWithWriter(output); err != nil {
 		gologger.Error().Msgf("failed to write output to file got %v", err)
 	}
 
 }
+
+// closeOutput flushes and closes output file (including gzip layer if any)
+func closeOutput(output io.Closer) {
+	if err := output.Close(); err != nil {
+		gologger.Error().Msgf("failed to close output file got %v", err)
+	}
+}
@@ -1,13 +1,18 @@
 package alterx
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	_ "embed"
 
 	"github.com/projectdiscovery/gologger"
 	fileutil "github.com/projectdiscovery/utils/file"
+	sliceutil "github.com/projectdiscovery/utils/slice"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,39 +22,136 @@ var DefaultPermutationsBin []byte
 // DefaultConfig contains default patterns and payloads
 var DefaultConfig Config
 
+// FilePayloadPrefix is the prefix of payload values that are loaded from a file
+// containing one payload per line (ex: `word: "file:wordlists/words.txt"`)
+const FilePayloadPrefix = "file:"
+
 type Config struct {
 	Patterns []string            `yaml:"patterns"`
 	Payloads map[string][]string `yaml:"payloads"`
 }
 
+// rawConfig is Config as present in file where payloads can either be a list or a single value
+type rawConfig struct {
+	Patterns []string                 `yaml:"patterns"`
+	Payloads map[string]payloadValues `yaml:"payloads"`
+}
+
+// payloadValues is a list of payloads that can also be unmarshalled from a single value
+type payloadValues []string
+
+// UnmarshalYAML implements yaml.Unmarshaler interface
+func (p *payloadValues) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = []string{value.Value}
+		return nil
+	}
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+	*p = values
+	return nil
+}
+
 // NewConfig reads config from file
 func NewConfig(filePath string) (*Config, error) {
 	bin, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	var cfg Config
-	if err = yaml.Unmarshal(bin, &cfg); err != nil {
+	var raw rawConfig
+	if err = yaml.Unmarshal(bin, &raw); err != nil {
 		return nil, err
 	}
-
-	var words []string
-	for _, p := range cfg.Payloads["word"] {
-		if !fileutil.FileExists(p) {
-			words = append(words, p)
-		} else {
-			wordBytes, err := os.ReadFile(p)
-			if err != nil {
-				gologger.Error().Msgf("failed to read wordlist from %v got %v", p, err)
+	cfg := Config{Patterns: raw.Patterns, Payloads: map[string][]string{}}
+	for k, values := range raw.Payloads {
+		for _, v := range values {
+			if !strings.HasPrefix(v, FilePayloadPrefix) {
+				cfg.Payloads[k] = append(cfg.Payloads[k], v)
 				continue
 			}
-			words = append(words, strings.Fields(string(wordBytes))...)
+			// relative paths are resolved against directory of config file
+			payloadFile := strings.TrimPrefix(v, FilePayloadPrefix)
+			if !filepath.IsAbs(payloadFile) {
+				payloadFile = filepath.Join(filepath.Dir(filePath), payloadFile)
+			}
+			payloads, err := ReadLinesFromFile(payloadFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %v payloads from %v got %v", k, payloadFile, err)
+			}
+			cfg.Payloads[k] = append(cfg.Payloads[k], payloads...)
+		}
+	}
+
+	if len(cfg.Payloads["word"]) > 0 {
+		var words []string
+		for _, p := range cfg.Payloads["word"] {
+			if !fileutil.FileExists(p) {
+				words = append(words, p)
+			} else {
+				lines, err := ReadLinesFromFile(p)
+				if err != nil {
+					gologger.Error().Msgf("failed to read wordlist from %v got %v", p, err)
+					continue
+				}
+				for _, line := range lines {
+					words = append(words, strings.Fields(line)...)
+				}
+			}
 		}
+		cfg.Payloads["word"] = words
 	}
-	cfg.Payloads["word"] = words
 	return &cfg, nil
 }
 
+// CommentPrefix marks a line of pattern/payload file as comment
+const CommentPrefix = "#"
+
+// ReadLinesFromFile reads file containing one entry per line (ex: payloads, patterns)
+// blank lines and lines starting with CommentPrefix are skipped
+func ReadLinesFromFile(filePath string) ([]string, error) {
+	bin, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(bin))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, CommentPrefix) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// NewConfigFromFiles reads and merges configs from given files in order.
+// patterns of all files are appended in order and duplicate patterns are removed keeping first occurrence.
+// later files take precedence for payloads i.e payloads of a key from a later file are placed ahead
+// of payloads of same key from earlier files (and so are used first and kept by MaxPayloadsPerVar)
+// and a payload present in multiple files keeps position from the latest file
+func NewConfigFromFiles(filePaths ...string) (*Config, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("no config files provided")
+	}
+	merged := &Config{Payloads: map[string][]string{}}
+	for _, filePath := range filePaths {
+		cfg, err := NewConfig(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config %v got %v", filePath, err)
+		}
+		merged.Patterns = append(merged.Patterns, cfg.Patterns...)
+		for k, v := range cfg.Payloads {
+			merged.Payloads[k] = append(append([]string{}, v...), merged.Payloads[k]...)
+		}
+	}
+	merged.Patterns = sliceutil.Dedupe(merged.Patterns)
+	for k, v := range merged.Payloads {
+		merged.Payloads[k] = sliceutil.Dedupe(v)
+	}
+	return merged, nil
+}
+
 func init() {
 	if err := yaml.Unmarshal(DefaultPermutationsBin, &DefaultConfig); err != nil {
 		gologger.Error().Msgf("default wordlist not found: got %v", err)
@@ -0,0 +1,57 @@
+package alterx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFilePayloads(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "wordlists"), 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "wordlists", "words.txt"), []byte("# environments\ndev\nprod  \n\n   \n# stage\nstage\n"), 0644))
+	config := `patterns:
+  - "{{word}}.{{suffix}}"
+payloads:
+  word: "file:wordlists/words.txt"
+  number:
+    - "1"
+    - "file:wordlists/words.txt"
+`
+	configFile := filepath.Join(dir, "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte(config), 0644))
+
+	cfg, err := NewConfig(configFile)
+	require.Nil(t, err)
+	require.Equal(t, []string{"{{word}}.{{suffix}}"}, cfg.Patterns)
+	require.Equal(t, []string{"dev", "prod", "stage"}, cfg.Payloads["word"])
+	require.Equal(t, []string{"1", "dev", "prod", "stage"}, cfg.Payloads["number"])
+
+	require.Nil(t, os.WriteFile(configFile, []byte("payloads:\n  word: \"file:missing.txt\"\n"), 0644))
+	_, err = NewConfig(configFile)
+	require.NotNil(t, err)
+}
+
+func TestConfigFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	override := filepath.Join(dir, "override.yaml")
+	require.Nil(t, os.WriteFile(base, []byte("patterns:\n  - \"{{word}}.{{suffix}}\"\npayloads:\n  word: [dev, prod]\n"), 0644))
+	require.Nil(t, os.WriteFile(override, []byte("patterns:\n  - \"{{word}}.{{suffix}}\"\n  - \"{{env}}-{{sub}}.{{suffix}}\"\npayloads:\n  word: [prod, stage]\n  env: [qa]\n"), 0644))
+
+	cfg, err := NewConfigFromFiles(base, override)
+	require.Nil(t, err)
+	require.Equal(t, []string{"{{word}}.{{suffix}}", "{{env}}-{{sub}}.{{suffix}}"}, cfg.Patterns)
+	// payloads of later file go first and shared payload keeps position from later file
+	require.Equal(t, []string{"prod", "stage", "dev"}, cfg.Payloads["word"])
+	require.Equal(t, []string{"qa"}, cfg.Payloads["env"])
+
+	cfg, err = NewConfigFromFiles(override, base)
+	require.Nil(t, err)
+	require.Equal(t, []string{"dev", "prod", "stage"}, cfg.Payloads["word"])
+
+	_, err = NewConfigFromFiles(base, filepath.Join(dir, "missing.yaml"))
+	require.NotNil(t, err)
+}
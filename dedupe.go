@@ -0,0 +1,145 @@
+package alterx
+
+import (
+	"hash/fnv"
+	"math"
+
+	"github.com/projectdiscovery/utils/dedupe"
+)
+
+const (
+	// bloomFalsePositiveRate is false positive rate of bloom filter dedupe backend
+	bloomFalsePositiveRate = 0.001
+	// bloomMinElements is min no of elements bloom filter is sized for
+	bloomMinElements = 1 << 16
+	// bloomMaxInitialElements is max no of elements first bloom filter is sized for (~30MB)
+	// larger runs rely on growth instead of allocating for the (possibly saturated) estimate up front
+	bloomMaxInitialElements = 1 << 24
+	// bloomMaxBits is max size of bloom filter in bits (1GB)
+	bloomMaxBits = 1 << 33
+)
+
+// dedupeBackend returns dedupe backend of mutator as configured in options
+func (m *Mutator) dedupeBackend() dedupe.DedupeBackend {
+	count := m.EstimateCount()
+	switch {
+	case m.Options.DedupeBloom:
+		return newBloomBackend(count, bloomFalsePositiveRate)
+	case m.Options.DedupeDisk:
+		return dedupe.NewLevelDBBackend()
+	default:
+		return newDedupeBackend(dedupeSize(count, m.maxkeyLenInBytes), m.Options.DedupeMemoryLimit)
+	}
+}
+
+// bloomBackend is a probabilistic dedupe backend backed by bloom filters.
+// it never lets a duplicate through but may wrongly report a unique element as duplicate.
+// when more elements than estimated are added a new filter with twice the capacity and
+// half the false positive rate is added so that total false positive rate stays below 2x fpRate
+type bloomBackend struct {
+	filters []*bloomFilter
+	fpRate  float64 // false positive rate of last filter
+}
+
+// bloomFilter is a fixed size bloom filter
+type bloomFilter struct {
+	bits     []uint64
+	size     uint64 // no of bits
+	hashes   uint64 // no of hash functions
+	capacity int64  // no of elements filter is sized for
+	count    int64  // no of elements added
+}
+
+// newBloomBackend returns bloom filter sized for given no of elements and false positive rate
+func newBloomBackend(elements int64, fpRate float64) *bloomBackend {
+	if elements < bloomMinElements {
+		elements = bloomMinElements
+	}
+	if elements > bloomMaxInitialElements {
+		elements = bloomMaxInitialElements
+	}
+	return &bloomBackend{filters: []*bloomFilter{newBloomFilter(elements, fpRate)}, fpRate: fpRate}
+}
+
+// newBloomFilter returns bloom filter sized for given no of elements and false positive rate
+func newBloomFilter(elements int64, fpRate float64) *bloomFilter {
+	size, capacity := bloomSize(elements, fpRate)
+	hashes := math.Max(1, math.Round(float64(size)/float64(capacity)*math.Ln2))
+	return &bloomFilter{
+		bits:     make([]uint64, size/64),
+		size:     size,
+		hashes:   uint64(hashes),
+		capacity: capacity,
+	}
+}
+
+// bloomSize returns size in bits (multiple of 64) of bloom filter for given no of elements
+// and false positive rate along with no of elements it can hold. if size is clamped to
+// bloomMaxBits capacity is reduced so that false positive rate is still honored
+func bloomSize(elements int64, fpRate float64) (uint64, int64) {
+	bitsPerElement := -math.Log(fpRate) / (math.Ln2 * math.Ln2)
+	size := math.Ceil(float64(elements) * bitsPerElement)
+	if size > bloomMaxBits {
+		size = bloomMaxBits
+		elements = int64(size / bitsPerElement)
+	}
+	return (uint64(size) + 63) / 64 * 64, elements
+}
+
+// Upsert adds element to filter and returns false if it was (probably) already present
+func (b *bloomBackend) Upsert(elem string) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(elem))
+	sum := h.Sum64()
+	// double hashing i.e index_i = h1 + i*h2
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	last := len(b.filters) - 1
+	for _, f := range b.filters[:last] {
+		if f.contains(h1, h2) {
+			return false
+		}
+	}
+	if current := b.filters[last]; current.count >= current.capacity {
+		// filter is over capacity, add a bigger one with lower false positive rate
+		b.fpRate /= 2
+		b.filters = append(b.filters, newBloomFilter(saturatingMul(current.capacity, 2), b.fpRate))
+		last++
+	}
+	return b.filters[last].add(h1, h2)
+}
+
+// contains returns true if element with given hashes is (probably) present in filter
+func (f *bloomFilter) contains(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.hashes; i++ {
+		index := (h1 + i*h2) % f.size
+		if f.bits[index/64]&(uint64(1)<<(index%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add adds element with given hashes to filter and returns false if it was (probably) already present
+func (f *bloomFilter) add(h1, h2 uint64) bool {
+	exists := true
+	for i := uint64(0); i < f.hashes; i++ {
+		index := (h1 + i*h2) % f.size
+		word, bit := index/64, uint64(1)<<(index%64)
+		if f.bits[word]&bit == 0 {
+			exists = false
+			f.bits[word] |= bit
+		}
+	}
+	if !exists {
+		f.count++
+	}
+	return !exists
+}
+
+// IterCallback is a no-op since bloom filter does not store elements
+func (b *bloomBackend) IterCallback(callback func(elem string)) {}
+
+// Cleanup releases memory used by filters
+func (b *bloomBackend) Cleanup() {
+	b.filters = nil
+}
@@ -2,6 +2,8 @@ package alterx
 
 import (
 	"fmt"
+	"net"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -21,6 +23,17 @@ type Input struct {
 	MultiLevel []string // (Optional) store prefix of multi level subdomains
 }
 
+// InputError contains raw input that failed to parse along with the error
+type InputError struct {
+	Raw string
+	Err error
+}
+
+// Error implements error interface
+func (e InputError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Raw, e.Err)
+}
+
 // GetMap returns variables map of input
 func (i *Input) GetMap() map[string]interface{} {
 	m := map[string]interface{}{
@@ -43,26 +56,81 @@ func (i *Input) GetMap() map[string]interface{} {
 	return m
 }
 
-// NewInput parses URL to Input Vars
+// hostname returns normalized hostname of input
+func (i *Input) hostname() string {
+	switch {
+	case i.Sub != "" && i.Suffix != "":
+		return i.Sub + "." + i.Suffix
+	case i.Sub != "":
+		// rootless input
+		return i.Sub
+	case i.Suffix != "":
+		return i.Suffix
+	case i.ETLD != "":
+		return i.ETLD
+	default:
+		return i.TLD
+	}
+}
+
+// bareLabelRegex matches input containing a single label (ex: internal, api-dev)
+var bareLabelRegex = regexp.MustCompile(`^[\p{L}\p{N}_*-]+\.?$`)
+
+// extractHostname strips scheme, userinfo, port and path from input
+// and returns normalized hostname i.e lowercase, punycode and without trailing dot
+func extractHostname(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	URL, err := urlutil.Parse(input)
+	if err != nil {
+		return "", err
+	}
+	host := URL.Hostname()
+	if host == "" && bareLabelRegex.MatchString(input) {
+		// single label is parsed as path
+		host = input
+	}
+	// internationalized domain names are converted to punycode (A-label)
+	hostname := toASCIIHostname(normalizeHostname(host))
+	if hostname == "" {
+		return "", fmt.Errorf("input %v does not contain a hostname", input)
+	}
+	if net.ParseIP(hostname) != nil {
+		return "", fmt.Errorf("input %v is an ip address and not a domain name", input)
+	}
+	return hostname, nil
+}
+
+// NewInput parses URL to Input Vars. rootless input i.e single label
+// (ex: api-dev) only contains Sub
 func NewInput(inputURL string) (*Input, error) {
-	URL, err := urlutil.Parse(inputURL)
+	return NewInputWithRoot(inputURL, "")
+}
+
+// NewInputWithRoot parses URL to Input Vars. rootless input i.e single label
+// (ex: api-dev) is parsed as subdomain of defaultRoot (ex: api-dev.corp.local)
+// if defaultRoot is not empty
+func NewInputWithRoot(inputURL, defaultRoot string) (*Input, error) {
+	hostname, err := extractHostname(inputURL)
 	if err != nil {
 		return nil, err
 	}
 	// check if hostname contains *
-	if strings.Contains(URL.Hostname(), "*") {
-		if strings.HasPrefix(URL.Hostname(), "*.") {
-			tmp := strings.TrimPrefix(URL.Hostname(), "*.")
-			URL.Host = strings.Replace(URL.Host, URL.Hostname(), tmp, 1)
-		}
+	if strings.Contains(hostname, "*") {
+		hostname = strings.TrimPrefix(hostname, "*.")
 		// if * is present in middle ex: prod.*.hackerone.com
 		// skip it
-		if strings.Contains(URL.Hostname(), "*") {
+		if strings.Contains(hostname, "*") {
 			return nil, fmt.Errorf("input %v is not a valid url , skipping", inputURL)
 		}
 	}
+	if !strings.Contains(hostname, ".") {
+		if defaultRoot == "" {
+			return &Input{Sub: hostname}, nil
+		}
+		hostname += "." + toASCIIHostname(normalizeHostname(strings.TrimPrefix(defaultRoot, ".")))
+	}
 	ivar := &Input{}
-	suffix, _ := publicsuffix.PublicSuffix(URL.Hostname())
+	suffix, _ := publicsuffix.PublicSuffix(hostname)
 	if strings.Contains(suffix, ".") {
 		ivar.ETLD = suffix
 		arr := strings.Split(suffix, ".")
@@ -70,10 +138,10 @@ func NewInput(inputURL string) (*Input, error) {
 	} else {
 		ivar.TLD = suffix
 	}
-	rootDomain, err := publicsuffix.EffectiveTLDPlusOne(URL.Hostname())
+	rootDomain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
 	if err != nil {
 		// this happens if input domain does not have eTLD+1 at all ex: `.com` or `co.uk`
-		gologger.Warning().Msgf("input domain %v is eTLD/publicsuffix and not a valid domain name", URL.Hostname())
+		gologger.Warning().Msgf("input domain %v is eTLD/publicsuffix and not a valid domain name", hostname)
 		return ivar, nil
 	}
 	ivar.Root = rootDomain
@@ -83,7 +151,7 @@ func NewInput(inputURL string) (*Input, error) {
 		ivar.SLD = strings.TrimSuffix(rootDomain, "."+ivar.TLD)
 	}
 	// anything before root domain is subdomain
-	subdomainPrefix := strings.TrimSuffix(URL.Hostname(), rootDomain)
+	subdomainPrefix := strings.TrimSuffix(hostname, rootDomain)
 	subdomainPrefix = strings.TrimSuffix(subdomainPrefix, ".")
 	if strings.Contains(subdomainPrefix, ".") {
 		// this is a multi level subdomain
@@ -95,6 +163,6 @@ func NewInput(inputURL string) (*Input, error) {
 	} else {
 		ivar.Sub = subdomainPrefix
 	}
-	ivar.Suffix = strings.TrimPrefix(URL.Hostname(), ivar.Sub+".")
+	ivar.Suffix = strings.TrimPrefix(hostname, ivar.Sub+".")
 	return ivar, nil
 }
@@ -0,0 +1,28 @@
+package runner
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/projectdiscovery/alterx"
+)
+
+// FormatJSONL formats result as a JSON line (without line ending) to be written by alterx.Mutator.RunStructured
+func FormatJSONL(res alterx.Result) ([]byte, error) {
+	return json.Marshal(res)
+}
+
+// OpenOutput opens output file for writing. output is gzip compressed if file name ends with .gz
+// returned writer must be closed to flush all data
+func OpenOutput(path string) (io.WriteCloser, error) {
+	fs, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".gz") {
+		return alterx.NewGzipWriter(fs), nil
+	}
+	return fs, nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/projectdiscovery/alterx"
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/levels"
@@ -18,14 +19,22 @@ type Options struct {
 	Domains            goflags.StringSlice // Subdomains to use as base
 	Patterns           goflags.StringSlice // Input Patterns
 	Payloads           map[string][]string // Input Payloads/WordLists
+	DefaultRoot        string              // root domain of rootless inputs
 	Output             string
 	Config             string
-	PermutationConfig  string
+	PermutationConfig  goflags.StringSlice
 	Estimate           bool
+	JSONL              bool
+	OutputPrefix       string
+	OutputSuffix       string
+	CRLF               bool
 	DisableUpdateCheck bool
 	Verbose            bool
 	Silent             bool
 	Enrich             bool
+	Typosquat          bool
+	TypoGen            bool
+	TLDSwap            bool
 	Limit              int
 	MaxSize            int
 	// internal/unexported fields
@@ -42,11 +51,16 @@ func ParseFlags() *Options {
 		flagSet.StringSliceVarP(&opts.Domains, "list", "l", nil, "subdomains to use when creating permutations (stdin, comma-separated, file)", goflags.FileCommaSeparatedStringSliceOptions),
 		flagSet.StringSliceVarP(&opts.Patterns, "pattern", "p", nil, "custom permutation patterns input to generate (comma-seperated, file)", goflags.FileCommaSeparatedStringSliceOptions),
 		flagSet.RuntimeMapVarP(&opts.wordlists, "payload", "pp", nil, "custom payload pattern input to replace/use in key=value format (-pp 'word=words.txt')"),
+		flagSet.StringVarP(&opts.DefaultRoot, "default-root", "dr", "", "root domain to append to rootless inputs i.e single labels (ex: corp.local)"),
 	)
 
 	flagSet.CreateGroup("output", "Output",
 		flagSet.BoolVarP(&opts.Estimate, "estimate", "es", false, "estimate permutation count without generating payloads"),
-		flagSet.StringVarP(&opts.Output, "output", "o", "", "output file to write altered subdomain list"),
+		flagSet.StringVarP(&opts.Output, "output", "o", "", "output file to write altered subdomain list (gzip compressed if file ends with .gz)"),
+		flagSet.BoolVarP(&opts.JSONL, "jsonl", "j", false, "write output in JSONL(ines) format with pattern provenance"),
+		flagSet.StringVarP(&opts.OutputPrefix, "output-prefix", "opx", "", "prefix to add to each result (ex: https://)"),
+		flagSet.StringVarP(&opts.OutputSuffix, "output-suffix", "osx", "", "suffix to add to each result (ex: :8443)"),
+		flagSet.BoolVar(&opts.CRLF, "crlf", false, "use CRLF line endings in output (ex: for windows tooling)"),
 		flagSet.SizeVarP(&maxFileSize, "max-size", "ms", "", "Max export data size (kb, mb, gb, tb) (default mb)"),
 		flagSet.BoolVarP(&opts.Verbose, "verbose", "v", false, "display verbose output"),
 		flagSet.BoolVar(&opts.Silent, "silent", false, "display results only"),
@@ -56,8 +70,11 @@ func ParseFlags() *Options {
 	flagSet.CreateGroup("config", "Config",
 		flagSet.StringVar(&opts.Config, "config", "", `alterx cli config file (default '$HOME/.config/alterx/config.yaml')`),
 		flagSet.BoolVarP(&opts.Enrich, "enrich", "en", false, "enrich wordlist by extracting words from input"),
-		flagSet.StringVar(&opts.PermutationConfig, "ac", "", fmt.Sprintf(`alterx permutation config file (default '$HOME/.config/alterx/permutation_%v.yaml')`, version)),
+		flagSet.StringSliceVar(&opts.PermutationConfig, "ac", nil, fmt.Sprintf(`alterx permutation config file, can be repeated to merge configs in order (default '$HOME/.config/alterx/permutation_%v.yaml')`, version), goflags.StringSliceOptions),
 		flagSet.IntVar(&opts.Limit, "limit", 0, "limit the number of results to return (default 0)"),
+		flagSet.BoolVarP(&opts.Typosquat, "typosquat", "ts", false, "also generate homoglyph variants of input labels (ex: rn <=> m)"),
+		flagSet.BoolVarP(&opts.TypoGen, "typo-gen", "tg", false, "also generate single character typo variants of input labels (ex: mail => mial)"),
+		flagSet.BoolVarP(&opts.TLDSwap, "tld-swap", "tsw", false, "also generate inputs with common tlds swapped (ex: example.com => example.net)"),
 	)
 
 	flagSet.CreateGroup("update", "Update",
@@ -93,6 +110,15 @@ func ParseFlags() *Options {
 		}
 	}
 
+	// skip comments and blank lines in patterns (ex: loaded from file)
+	var patterns goflags.StringSlice
+	for _, v := range opts.Patterns {
+		if v = strings.TrimSpace(v); v != "" && !strings.HasPrefix(v, alterx.CommentPrefix) {
+			patterns = append(patterns, v)
+		}
+	}
+	opts.Patterns = patterns
+
 	opts.MaxSize = math.MaxInt
 	if maxFileSize > 0 {
 		opts.MaxSize = int(maxFileSize)
@@ -105,12 +131,15 @@ func ParseFlags() *Options {
 			continue
 		}
 		if fileutil.FileExists(value) {
-			bin, err := os.ReadFile(value)
+			lines, err := alterx.ReadLinesFromFile(value)
 			if err != nil {
 				gologger.Error().Msgf("failed to read wordlist %v got %v", value, err)
 				continue
 			}
-			wordlist := strings.Fields(string(bin))
+			var wordlist []string
+			for _, line := range lines {
+				wordlist = append(wordlist, strings.Fields(line)...)
+			}
 			opts.Payloads[k] = wordlist
 		} else {
 			opts.Payloads[k] = []string{value}
@@ -3,13 +3,16 @@ package alterx
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/projectdiscovery/fasttemplate"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/utils/dedupe"
 	errorutil "github.com/projectdiscovery/utils/errors"
@@ -19,10 +22,21 @@ import (
 var (
 	extractNumbers   = regexp.MustCompile(`[0-9]+`)
 	extractWords     = regexp.MustCompile(`[a-zA-Z0-9]+`)
-	extractWordsOnly = regexp.MustCompile(`[a-zA-Z]{3,}`)
-	DedupeResults    = true // Dedupe all results (default: true)
+	extractWordsOnly = regexp.MustCompile(`[a-zA-Z]+`) // filtered by Options.EnrichMinWordLen
+	// DedupeResults dedupes all results (default: true)
+	//
+	// Deprecated: use Options.DisableDedupe instead. Setting this to false
+	// disables dedupe for all Mutator instances.
+	DedupeResults = true
+	// errStopGeneration is returned by callbacks to stop generation early (ex: limit reached)
+	errStopGeneration = errors.New("stop generation")
+	// errPatternLimitReached is returned to stop generation of current pattern
+	errPatternLimitReached = errors.New("pattern limit reached")
 )
 
+// defaultProgressInterval is default value of Options.ProgressInterval
+const defaultProgressInterval = 1000
+
 // Mutator Options
 type Options struct {
 	// list of Domains to use as base
@@ -35,11 +49,201 @@ type Options struct {
 	Patterns []string
 	// Limits output results (0 = no limit)
 	Limit int
+	// LimitPerPattern limits output results of each pattern across all inputs (0 = no limit)
+	LimitPerPattern int
+	// MaxPerInput limits output results of each input across all patterns so that
+	// no single input dominates output (0 = no limit). remaining permutations of an input
+	// are skipped once it is reached and counted in RunStats.CappedInputs and RunStats.CappedValues
+	MaxPerInput int
+	// Interleave generates results of all (input, pattern) pairs in round-robin fashion
+	// so that a small Limit samples all patterns. Note: this keeps payloads of all pairs in memory
+	Interleave bool
 	// Enrich when true alterx extra possible words from input
 	// and adds them to default payloads word,number
 	Enrich bool
-	// MaxSize limits output data size
+	// EnrichMode selects payloads enriched when Enrich is enabled
+	// i.e EnrichWords, EnrichNumbers or EnrichBoth (empty = EnrichBoth)
+	EnrichMode string
+	// NumberNeighbors when used with Enrich also adds numbers within ±N of
+	// numbers extracted from input (ex: node17 with 1 => 16,17,18)
+	NumberNeighbors int
+	// EnrichMinWordLen is min length of letter only fragments extracted
+	// from input during enrichment (ex: api from api2) (default: 3)
+	EnrichMinWordLen int
+	// MaxSize limits output data size in bytes. ExecuteToSlice treats 0 as no limit
+	// while ExecuteWithWriter and Run write nothing if it is not set
 	MaxSize int
+	// DisableDedupe disables deduplication of results (default: false)
+	DisableDedupe bool
+	// DedupeDisk when true always uses disk backed (leveldb) dedupe instead of in-memory map
+	DedupeDisk bool
+	// DedupeMemoryLimit (optional) is estimated size of results in bytes above which disk backed
+	// dedupe is used instead of in-memory map (0 = 100MB)
+	DedupeMemoryLimit int
+	// DedupeBloom when true uses a bloom filter for dedupe which needs only a fraction of memory
+	// of other backends but wrongly drops ~0.1% of unique results (false positives). filter is sized
+	// using EstimateCount (up to ~30MB) and grows as needed keeping false positives below ~0.2%
+	DedupeBloom bool
+	// KnownHostsFile (optional) is path of file containing already known hosts (one per line)
+	// which are suppressed from output (ex: results of previous run)
+	KnownHostsFile string
+	// OpenDelim is the marker denoting start of a variable in patterns (default: {{)
+	OpenDelim string
+	// CloseDelim is the marker denoting end of a variable in patterns (default: }})
+	// if Patterns is empty default patterns are rewritten to use OpenDelim and CloseDelim
+	CloseDelim string
+	// ExcludeWords is list of words that are removed from all payloads (including enriched ones)
+	ExcludeWords []string
+	// ExcludeWordsIgnoreCase when true matches ExcludeWords case-insensitively
+	ExcludeWordsIgnoreCase bool
+	// IncludeRegex (optional) only keeps results matching this regex
+	IncludeRegex string
+	// ExcludeRegex (optional) drops results matching this regex (takes precedence over IncludeRegex)
+	ExcludeRegex string
+	// MaxPayloadsPerVar (optional) limits number of payloads used per variable in a pattern (0 = no limit)
+	MaxPayloadsPerVar int
+	// PayloadWeights (optional) contains weights of payloads per variable (ex: word => api => 10)
+	// payloads are ordered by weight in descending order so that MaxPayloadsPerVar keeps the most likely ones
+	PayloadWeights map[string]map[string]int
+	// AllowDuplicateTokens when true does not skip payloads that are equal to a token (label or
+	// dash/underscore separated part) already present in pattern/input (ex: api-api.scanme.sh)
+	AllowDuplicateTokens bool
+	// StrictInput when true returns error if any of input domains fails to parse
+	StrictInput bool
+	// DefaultRoot is root domain appended to rootless inputs i.e single labels (ex: api-dev => api-dev.corp.local)
+	// without it rootless inputs only provide {{sub}}
+	DefaultRoot string
+	// DNSValid when true drops results that are not valid dns names (RFC 1035 label/length/charset rules)
+	DNSValid bool
+	// NormalizeOutput lowercases results and strips trailing dot before dedupe
+	NormalizeOutput bool
+	// UnicodeOutput writes internationalized results in unicode (U-label) form
+	// instead of default punycode (A-label) form
+	UnicodeOutput bool
+	// LineEnding is terminator written after each result by ExecuteWithWriter (\n or \r\n, empty = \n)
+	LineEnding string
+	// OutputPrefix (optional) is prepended to each result after dedupe (ex: https://)
+	OutputPrefix string
+	// OutputSuffix (optional) is appended to each result after dedupe (ex: :8443)
+	OutputSuffix string
+	// FilterFunc (optional) is invoked for each result after dedupe and regex/dns filters.
+	// results for which it returns false are dropped and not counted towards Limit
+	FilterFunc func(subdomain string) bool
+	// TransformFunc (optional) rewrites each result after dedupe and filtering and
+	// transformed value is written instead. results transformed to empty string are dropped
+	TransformFunc func(subdomain string) string
+	// Typosquat when true also generates visually confusable variants of each input label
+	// (ex: rn <=> m, 0 <=> o) for lookalike domain discovery. public suffix is never changed
+	// and variants go through same dedupe, filters and limits as permutations
+	Typosquat bool
+	// TyposquatMaxEdits is max no of homoglyph substitutions per label (default: 1)
+	TyposquatMaxEdits int
+	// Homoglyphs (optional) maps character sequences to their confusable replacements
+	// used by Typosquat (default: DefaultHomoglyphs)
+	Homoglyphs map[string][]string
+	// TypoGen when true also generates typo variants of each input label using character
+	// insertion, deletion, substitution and adjacent transposition (ex: mail => mial, mai).
+	// like Typosquat public suffix is never changed and variants are deduped, filtered and limited
+	TypoGen bool
+	// TypoMaxEdits is max no of character operations per label used by TypoGen (default: 1, max: MaxTypoEdits).
+	// variants are not deduped before output dedupe i.e with DisableDedupe same variant may be written more than once
+	TypoMaxEdits int
+	// TLDSwap when true also generates each input with its public suffix (tld/etld)
+	// replaced by each of SwapTLDs (ex: api.example.com => api.example.net)
+	TLDSwap bool
+	// SwapTLDs (optional) are public suffixes used by TLDSwap (default: DefaultSwapTLDs)
+	SwapTLDs []string
+	// DistinctPositional skips combinations where positional variables of same payload
+	// have equal values (ex: api-api.scanme.sh for {{word:0}}-{{word:1}}.{{root}})
+	DistinctPositional bool
+	// SampleSize (optional) generates only given number of random distinct payload combinations
+	// per (input, pattern) instead of enumerating all of them (0 = disabled)
+	SampleSize int
+	// Seed is used to seed random sampling. same seed produces same output for same input (0 = random seed)
+	Seed int64
+	// Shuffle when true writes results in random order (seeded by Seed) instead of grouped by pattern.
+	// Note: all results are kept in memory until generation finishes unless ShuffleWindow is set
+	Shuffle bool
+	// ShuffleWindow (optional) limits no of results kept in memory by Shuffle. results are only
+	// shuffled within window of this size i.e less random but bounded memory (0 = all results)
+	ShuffleWindow int
+	// CoverageReport when true records no of results generated by each pattern for each input
+	// which can be accessed via Coverage after execution
+	CoverageReport bool
+	// OnProgress (optional) is invoked every ProgressInterval results written by ExecuteWithWriter
+	// with number of results written so far and estimated total (including duplicates)
+	OnProgress func(done, total int)
+	// ProgressInterval is number of results written between two OnProgress callbacks
+	// (default: 1000, < 0 = OnProgress is only invoked once after all results are written)
+	ProgressInterval int
+
+	// internal/unexported fields
+	includeRegex *regexp.Regexp
+	excludeRegex *regexp.Regexp
+}
+
+// Validate validates mutator options. empty values are treated as their defaults
+func (o *Options) Validate() error {
+	openDelim, closeDelim := o.OpenDelim, o.CloseDelim
+	if openDelim == "" {
+		openDelim = ParenthesisOpen
+	}
+	if closeDelim == "" {
+		closeDelim = ParenthesisClose
+	}
+	if openDelim == closeDelim {
+		return fmt.Errorf("open and close template delimiters must be distinct got %v", openDelim)
+	}
+	if o.DefaultRoot != "" {
+		if root := toASCIIHostname(normalizeHostname(strings.TrimPrefix(o.DefaultRoot, "."))); !strings.Contains(root, ".") || !isValidDNSName(root) {
+			return fmt.Errorf("invalid default root %v", o.DefaultRoot)
+		}
+	}
+	switch o.EnrichMode {
+	case "", EnrichWords, EnrichNumbers, EnrichBoth:
+	default:
+		return fmt.Errorf("invalid enrich mode %q (supported: %v, %v, %v)", o.EnrichMode, EnrichWords, EnrichNumbers, EnrichBoth)
+	}
+	if o.EnrichMinWordLen < 0 {
+		return fmt.Errorf("enrich min word length must be non-negative got %v", o.EnrichMinWordLen)
+	}
+	if o.TyposquatMaxEdits < 0 {
+		return fmt.Errorf("typosquat max edits must be non-negative got %v", o.TyposquatMaxEdits)
+	}
+	if o.TypoMaxEdits < 0 || o.TypoMaxEdits > MaxTypoEdits {
+		return fmt.Errorf("typo max edits must be between 0 and %v got %v", MaxTypoEdits, o.TypoMaxEdits)
+	}
+	if o.NumberNeighbors < 0 {
+		return fmt.Errorf("number neighbors must be non-negative got %v", o.NumberNeighbors)
+	}
+	if o.LineEnding != "" && o.LineEnding != "\n" && o.LineEnding != "\r\n" {
+		return fmt.Errorf("line ending must be \\n or \\r\\n got %q", o.LineEnding)
+	}
+	var err error
+	o.includeRegex, o.excludeRegex = nil, nil
+	if o.IncludeRegex != "" {
+		if o.includeRegex, err = regexp.Compile(o.IncludeRegex); err != nil {
+			return fmt.Errorf("invalid include regex %v got %v", o.IncludeRegex, err)
+		}
+	}
+	if o.ExcludeRegex != "" {
+		if o.excludeRegex, err = regexp.Compile(o.ExcludeRegex); err != nil {
+			return fmt.Errorf("invalid exclude regex %v got %v", o.ExcludeRegex, err)
+		}
+	}
+	return nil
+}
+
+// matchesRegex returns true if value passes include/exclude regex filters
+// exclude regex takes precedence over include regex
+func (o *Options) matchesRegex(value string) bool {
+	if o.excludeRegex != nil && o.excludeRegex.MatchString(value) {
+		return false
+	}
+	if o.includeRegex != nil && !o.includeRegex.MatchString(value) {
+		return false
+	}
+	return true
 }
 
 // Mutator
@@ -47,9 +251,20 @@ type Mutator struct {
 	Options      *Options
 	payloadCount int
 	Inputs       []*Input // all processed inputs
-	timeTaken    time.Duration
+	stats        RunStats
+	inputErrors  []InputError // inputs that failed to parse
+	rng          *rand.Rand
+	// payloads of variables defined inline in patterns (ex: {{number:01-50}}, {{(dev|prod)}})
+	inlinePayloads map[string][]string
+	// precompiled patterns
+	templates map[string]*Template
+	// hosts loaded from KnownHostsFile
+	knownHosts map[string]struct{}
+	// input => pattern => no of results (only if CoverageReport is enabled)
+	coverage map[string]map[string]int
 	// internal or unexported variables
 	maxkeyLenInBytes int
+	delims           *delimiters
 }
 
 // New creates and returns new mutator instance from options
@@ -62,14 +277,56 @@ func New(opts *Options) (*Mutator, error) {
 		if len(DefaultConfig.Payloads) == 0 {
 			return nil, fmt.Errorf("something went wrong, `DefaultWordList` and input wordlist are empty")
 		}
-		opts.Payloads = DefaultConfig.Payloads
+		// copy default payloads so that changes (dedupe/exclude/enrich) are not reflected in DefaultConfig
+		for k, v := range DefaultConfig.Payloads {
+			opts.Payloads[k] = v
+		}
 	}
+	// default patterns are not validated against payloads since they are used
+	// only if all variables are available (ex: payloads given by user may not contain region)
+	customPatterns := len(opts.Patterns) > 0
 	if len(opts.Patterns) == 0 {
 		if len(DefaultConfig.Patterns) == 0 {
 			return nil, fmt.Errorf("something went wrong,`DefaultPatters` and input patterns are empty")
 		}
 		opts.Patterns = DefaultConfig.Patterns
 	}
+	if opts.OpenDelim == "" {
+		opts.OpenDelim = ParenthesisOpen
+	}
+	if opts.CloseDelim == "" {
+		opts.CloseDelim = ParenthesisClose
+	}
+	if !customPatterns && (opts.OpenDelim != ParenthesisOpen || opts.CloseDelim != ParenthesisClose) {
+		// default patterns use {{ }} and are rewritten to use configured delimiters
+		replacer := strings.NewReplacer(ParenthesisOpen, opts.OpenDelim, ParenthesisClose, opts.CloseDelim)
+		patterns := make([]string, 0, len(DefaultConfig.Patterns))
+		for _, pattern := range DefaultConfig.Patterns {
+			patterns = append(patterns, replacer.Replace(pattern))
+		}
+		opts.Patterns = patterns
+	}
+	if opts.LineEnding == "" {
+		opts.LineEnding = "\n"
+	}
+	if opts.EnrichMode == "" {
+		opts.EnrichMode = EnrichBoth
+	}
+	if opts.EnrichMinWordLen == 0 {
+		opts.EnrichMinWordLen = defaultEnrichMinWordLen
+	}
+	if opts.TyposquatMaxEdits == 0 {
+		opts.TyposquatMaxEdits = defaultTyposquatMaxEdits
+	}
+	if opts.TypoMaxEdits == 0 {
+		opts.TypoMaxEdits = defaultTypoMaxEdits
+	}
+	if opts.ProgressInterval == 0 {
+		opts.ProgressInterval = defaultProgressInterval
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
 	// purge duplicates if any
 	for k, v := range opts.Payloads {
 		dedupe := sliceutil.Dedupe(v)
@@ -78,138 +335,557 @@ func New(opts *Options) (*Mutator, error) {
 			opts.Payloads[k] = dedupe
 		}
 	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 	m := &Mutator{
 		Options: opts,
+		delims:  newDelimiters(opts.OpenDelim, opts.CloseDelim),
+		rng:     rand.New(rand.NewSource(seed)),
+	}
+	// remove excluded words if any
+	for k, v := range opts.Payloads {
+		opts.Payloads[k] = m.removeExcluded(v)
 	}
 	if err := m.validatePatterns(); err != nil {
 		return nil, err
 	}
+	if customPatterns {
+		if err := m.validatePatternVars(); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.loadKnownHosts(); err != nil {
+		return nil, err
+	}
 	if err := m.prepareInputs(); err != nil {
 		return nil, err
 	}
 	if opts.Enrich {
-		m.enrichPayloads()
+		m.enrichPayloads(m.Inputs)
 	}
+	m.sortPayloadsByWeight()
+	m.warnEmptyPayloads()
 	return m, nil
 }
 
 // Execute calculates all permutations using input wordlist and patterns
 // and writes them to a string channel
 func (m *Mutator) Execute(ctx context.Context) <-chan string {
-	var maxBytes int
-	if DedupeResults {
-		count := m.EstimateCount()
-		maxBytes = count * m.maxkeyLenInBytes
-	}
-
 	results := make(chan string, len(m.Options.Patterns))
 	go func() {
-		now := time.Now()
-		for _, v := range m.Inputs {
-			varMap := getSampleMap(v.GetMap(), m.Options.Payloads)
-			for _, pattern := range m.Options.Patterns {
-				if err := checkMissing(pattern, varMap); err == nil {
-					statement := Replace(pattern, v.GetMap())
-					select {
-					case <-ctx.Done():
-						return
-					default:
-						m.clusterBomb(statement, results)
-					}
-				} else {
-					gologger.Warning().Msgf("%v : failed to evaluate pattern %v. skipping", err.Error(), pattern)
-				}
+		defer close(results)
+		structured := m.ExecuteStructured(ctx)
+		// drain remaining results so that producer is never blocked if context is cancelled
+		defer func() {
+			for range structured {
+			}
+		}()
+		for res := range structured {
+			select {
+			case <-ctx.Done():
+				return
+			case results <- res.Subdomain:
 			}
 		}
-		m.timeTaken = time.Since(now)
-		close(results)
 	}()
+	return results
+}
 
-	if DedupeResults {
-		// drain results
-		d := dedupe.NewDedupe(results, maxBytes)
-		d.Drain()
-		return d.GetResults()
-	}
+// ExecuteStructured calculates all permutations using input wordlist and patterns
+// and writes them along with their provenance to a Result channel
+func (m *Mutator) ExecuteStructured(ctx context.Context) <-chan Result {
+	results := make(chan Result, len(m.Options.Patterns))
+	go func() {
+		defer close(results)
+		_ = m.generate(ctx, func(res Result) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case results <- res:
+				return nil
+			}
+		})
+	}()
 	return results
 }
 
-// ExecuteWithWriter executes Mutator and writes results directly to type that implements io.Writer interface
-func (m *Mutator) ExecuteWithWriter(Writer io.Writer) error {
-	if Writer == nil {
-		return errorutil.NewWithTag("alterx", "writer destination cannot be nil")
+// ExecuteWithCallback calculates all permutations using input wordlist and patterns
+// and invokes callback for each generated subdomain. generation is aborted
+// and error is returned if callback returns an error
+func (m *Mutator) ExecuteWithCallback(ctx context.Context, fn func(subdomain string) error) error {
+	if fn == nil {
+		return errorutil.NewWithTag("alterx", "callback cannot be nil")
 	}
-	resChan := m.Execute(context.TODO())
-	m.payloadCount = 0
-	maxFileSize := m.Options.MaxSize
-	for {
-		value, ok := <-resChan
-		if !ok {
-			gologger.Info().Msgf("Generated %v permutations in %v", m.payloadCount, m.Time())
-			return nil
+	return m.generate(ctx, func(res Result) error {
+		return fn(res.Subdomain)
+	})
+}
+
+// generate calculates all permutations and invokes callback for each (deduped and filtered) result
+// in random order if Shuffle is enabled
+func (m *Mutator) generate(ctx context.Context, callback func(res Result) error) error {
+	if m.Options.Seed != 0 {
+		// same seed produces same sample/shuffle order in every run of mutator
+		m.rng = rand.New(rand.NewSource(m.Options.Seed))
+	}
+	if !m.Options.Shuffle {
+		return m.generateAll(ctx, callback)
+	}
+	buffer := newShuffleBuffer(m.Options.ShuffleWindow, m.rng, callback)
+	if err := m.generateAll(ctx, buffer.add); err != nil {
+		return err
+	}
+	return buffer.flush()
+}
+
+// generateAll calculates all permutations and invokes callback for each (deduped and filtered) result
+func (m *Mutator) generateAll(ctx context.Context, callback func(res Result) error) error {
+	var backend dedupe.DedupeBackend
+	if m.dedupeEnabled() {
+		backend = m.dedupeBackend()
+		defer backend.Cleanup()
+	}
+	// emit normalizes, dedupes and filters results before sending them to callback
+	// and returns true if result was sent to callback
+	emit := func(res Result) (bool, error) {
+		m.stats.Generated++
+		if m.Options.NormalizeOutput {
+			res.Subdomain = normalizeHostname(res.Subdomain)
 		}
-		if m.Options.Limit > 0 && m.payloadCount == m.Options.Limit {
-			// we can't early exit, due to abstraction we have to conclude the elaboration to drain all dedupers
-			continue
+		// results are always deduped in punycode (A-label) form
+		res.Subdomain = toASCIIHostname(res.Subdomain)
+		if backend != nil && !backend.Upsert(res.Subdomain) {
+			m.stats.Duplicates++
+			return false, nil
 		}
-		if maxFileSize <= 0 {
-			// drain all dedupers when max-file size reached
-			continue
+		if _, ok := m.knownHosts[res.Subdomain]; ok {
+			m.stats.Duplicates++
+			return false, nil
+		}
+		if !m.Options.matchesRegex(res.Subdomain) {
+			m.stats.Filtered++
+			return false, nil
 		}
+		if m.Options.DNSValid && !isValidDNSName(res.Subdomain) {
+			m.stats.Filtered++
+			return false, nil
+		}
+		if m.Options.UnicodeOutput {
+			res.Subdomain = toUnicodeHostname(res.Subdomain)
+		}
+		if m.Options.FilterFunc != nil && !m.Options.FilterFunc(res.Subdomain) {
+			m.stats.Filtered++
+			return false, nil
+		}
+		if m.Options.TransformFunc != nil {
+			if res.Subdomain = m.Options.TransformFunc(res.Subdomain); res.Subdomain == "" {
+				m.stats.Filtered++
+				return false, nil
+			}
+		}
+		if m.Options.OutputPrefix != "" || m.Options.OutputSuffix != "" {
+			// results starting with hyphen are never written but prefix would hide it
+			// from output so such results are filtered before wrapping
+			if strings.HasPrefix(res.Subdomain, "-") {
+				m.stats.Filtered++
+				return false, nil
+			}
+			res.Subdomain = m.Options.OutputPrefix + res.Subdomain + m.Options.OutputSuffix
+		}
+		return true, callback(res)
+	}
 
-		if strings.HasPrefix(value, "-") {
-			continue
+	m.stats = RunStats{}
+	if m.Options.CoverageReport {
+		m.coverage = map[string]map[string]int{}
+	}
+	now := time.Now()
+	defer func() {
+		m.stats.TimeTaken = time.Since(now)
+	}()
+	// no of results emitted by each pattern (across all inputs)
+	patternCount := make([]int, len(m.Options.Patterns))
+	// no of results emitted for each input (across all patterns)
+	inputCount := map[*Input]int{}
+	// no of values generated by each pattern for each input (including duplicates)
+	generated := map[*Input][]int{}
+	limitReached := func(input *Input, index int) bool {
+		if m.Options.MaxPerInput > 0 && inputCount[input] >= m.Options.MaxPerInput {
+			return true
+		}
+		return m.Options.LimitPerPattern > 0 && patternCount[index] >= m.Options.LimitPerPattern
+	}
+	// emitPattern emits value generated by pattern at given index for given input
+	// and enforces LimitPerPattern and MaxPerInput
+	emitPattern := func(input *Input, index int, value string) error {
+		if generated[input] == nil {
+			generated[input] = make([]int, len(m.Options.Patterns))
+		}
+		generated[input][index]++
+		emitted, err := emit(Result{Subdomain: value, Pattern: m.Options.Patterns[index], Source: SourceDefault})
+		if err != nil {
+			return err
+		}
+		if emitted {
+			if m.coverage != nil {
+				m.coverage[input.hostname()][m.Options.Patterns[index]]++
+			}
+			patternCount[index]++
+			inputCount[input]++
+			if m.Options.MaxPerInput > 0 && inputCount[input] == m.Options.MaxPerInput {
+				m.stats.CappedInputs++
+			}
+			if limitReached(input, index) {
+				return errPatternLimitReached
+			}
+		}
+		return nil
+	}
+	var err error
+	if m.Options.Interleave {
+		err = m.generateInterleaved(ctx, emitPattern, limitReached)
+	} else {
+		err = m.generatePatterns(ctx, emitPattern, limitReached)
+	}
+	if m.Options.MaxPerInput > 0 {
+		m.stats.CappedValues = m.cappedValues(inputCount, generated, patternCount)
+	}
+	if err != nil {
+		return err
+	}
+	if m.Options.Typosquat {
+		if err := m.generateTyposquats(ctx, emit); err != nil {
+			return err
 		}
+	}
+	if m.Options.TypoGen {
+		if err := m.generateTypos(ctx, emit); err != nil {
+			return err
+		}
+	}
+	if m.Options.TLDSwap {
+		return m.generateTLDSwaps(ctx, emit)
+	}
+	return nil
+}
 
-		outputData := []byte(value + "\n")
-		if len(outputData) > maxFileSize {
-			maxFileSize = 0
-			continue
+// generatePatterns generates all results of each (input, pattern) before moving on to next one
+func (m *Mutator) generatePatterns(ctx context.Context, emitPattern func(input *Input, index int, value string) error, limitReached func(input *Input, index int) bool) error {
+	for _, v := range m.Inputs {
+		varMap := m.sampleMap(v)
+		for index, pattern := range m.Options.Patterns {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if limitReached(v, index) {
+				continue
+			}
+			statement, ok := m.evaluatePattern(pattern, v, varMap)
+			if !ok {
+				continue
+			}
+			err := m.clusterBomb(statement, func(value string) error {
+				return emitPattern(v, index, value)
+			})
+			if err != nil && !errors.Is(err, errPatternLimitReached) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// generateInterleaved generates results in round-robin fashion i.e one result of each (input, pattern)
+// at a time instead of generating all results of a pattern before moving on to next one
+func (m *Mutator) generateInterleaved(ctx context.Context, emitPattern func(input *Input, index int, value string) error, limitReached func(input *Input, index int) bool) error {
+	type generator struct {
+		input    *Input
+		index    int // index of pattern
+		template string
+		compiled *Template
+		iterator payloadIterator
+	}
+	var generators []*generator
+	for _, v := range m.Inputs {
+		varMap := m.sampleMap(v)
+		for index, pattern := range m.Options.Patterns {
+			statement, ok := m.evaluatePattern(pattern, v, varMap)
+			if !ok {
+				continue
+			}
+			generators = append(generators, &generator{
+				input:    v,
+				index:    index,
+				template: statement,
+				compiled: m.compile(statement),
+				iterator: m.payloadIterator(statement),
+			})
 		}
+	}
+	for len(generators) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		active := generators[:0]
+		for _, g := range generators {
+			if limitReached(g.input, g.index) {
+				continue
+			}
+			varMap, ok := g.iterator.Next()
+			if !ok {
+				continue
+			}
+			active = append(active, g)
+			value, ok := m.expand(g.template, g.compiled, varMap)
+			if !ok {
+				continue
+			}
+			if err := emitPattern(g.input, g.index, value); err != nil && !errors.Is(err, errPatternLimitReached) {
+				return err
+			}
+		}
+		generators = active
+	}
+	return nil
+}
+
+// evaluatePattern replaces input variables in pattern and returns statement
+// containing only payload variables. it returns false if pattern cannot be evaluated for given input
+func (m *Mutator) evaluatePattern(pattern string, input *Input, varMap map[string]interface{}) (string, bool) {
+	if err := checkMissing(pattern, varMap, m.delims); err != nil {
+		gologger.Warning().Msgf("%v : failed to evaluate pattern %v. skipping", err.Error(), pattern)
+		m.stats.SkippedPatterns++
+		return "", false
+	}
+	if m.coverage != nil {
+		if m.coverage[input.hostname()] == nil {
+			m.coverage[input.hostname()] = map[string]int{}
+		}
+		// patterns that can be evaluated are recorded even if they generate no results
+		if _, ok := m.coverage[input.hostname()][pattern]; !ok {
+			m.coverage[input.hostname()][pattern] = 0
+		}
+	}
+	return m.replacePattern(pattern, input.GetMap()), true
+}
 
-		n, err := Writer.Write(outputData)
+// Coverage returns no of results generated by each pattern for each input (input => pattern => count)
+// in last execution. only patterns that could be evaluated for an input are present.
+// it returns nil if CoverageReport is not enabled
+func (m *Mutator) Coverage() map[string]map[string]int {
+	return m.coverage
+}
+
+// ExecuteWithWriter executes Mutator and writes results directly to type that implements io.Writer interface
+// generation is stopped as soon as Limit or MaxSize is reached
+func (m *Mutator) ExecuteWithWriter(Writer io.Writer) error {
+	_, err := m.Run(context.TODO(), Writer)
+	return err
+}
+
+// Run executes Mutator, writes results to writer and returns statistics of the run
+// generation is stopped as soon as Limit or MaxSize is reached or context is cancelled
+func (m *Mutator) Run(ctx context.Context, Writer io.Writer) (*RunStats, error) {
+	return m.RunStructured(ctx, Writer, func(res Result) ([]byte, error) {
+		return []byte(res.Subdomain), nil
+	})
+}
+
+// RunStructured is like Run but writes each result in format returned by format (ex: json.Marshal)
+// followed by LineEnding. Limit and MaxSize are enforced on formatted output
+func (m *Mutator) RunStructured(ctx context.Context, Writer io.Writer, format func(res Result) ([]byte, error)) (*RunStats, error) {
+	if Writer == nil {
+		return nil, errorutil.NewWithTag("alterx", "writer destination cannot be nil")
+	}
+	if format == nil {
+		return nil, errorutil.NewWithTag("alterx", "format cannot be nil")
+	}
+	var total int
+	if m.Options.OnProgress != nil {
+		total = clampInt(m.EstimateCount())
+	}
+	m.payloadCount = 0
+	limiter := newOutputLimiter(m.Options.Limit, m.Options.MaxSize, len(m.Options.LineEnding))
+	err := m.generate(ctx, func(res Result) error {
+		data, err := format(res)
 		if err != nil {
 			return err
 		}
-		// update maxFileSize limit after each write
-		maxFileSize -= n
-		m.payloadCount++
+		if ok, err := limiter.check(res.Subdomain, len(data)); !ok {
+			return err
+		}
+		n, err := Writer.Write(append(data, m.Options.LineEnding...))
+		if err != nil {
+			return err
+		}
+		err = limiter.add(n)
+		m.payloadCount = limiter.count
+		if m.Options.OnProgress != nil && m.Options.ProgressInterval > 0 && m.payloadCount%m.Options.ProgressInterval == 0 {
+			m.Options.OnProgress(m.payloadCount, total)
+		}
+		return err
+	})
+	m.stats.Written = m.payloadCount
+	stats := m.stats
+	if err != nil && !errors.Is(err, errStopGeneration) {
+		return &stats, err
+	}
+	if m.Options.OnProgress != nil {
+		m.Options.OnProgress(m.payloadCount, total)
+	}
+	gologger.Info().Msgf("Generated %v permutations in %v", m.payloadCount, m.Time())
+	return &stats, nil
+}
+
+// ExecuteToSlice executes Mutator and returns all (deduped) results
+// generation is stopped as soon as Limit or MaxSize is reached or context is cancelled
+func (m *Mutator) ExecuteToSlice(ctx context.Context) ([]string, error) {
+	var results []string
+	maxSize := m.Options.MaxSize
+	if maxSize <= 0 {
+		// results are not written anywhere so size is only limited if explicitly set
+		maxSize = math.MaxInt
+	}
+	limiter := newOutputLimiter(m.Options.Limit, maxSize, len(m.Options.LineEnding))
+	err := m.ExecuteWithCallback(ctx, func(value string) error {
+		if ok, err := limiter.check(value, len(value)); !ok {
+			return err
+		}
+		results = append(results, value)
+		return limiter.add(len(value) + len(m.Options.LineEnding))
+	})
+	if err != nil && !errors.Is(err, errStopGeneration) {
+		return results, err
+	}
+	return results, nil
+}
+
+// outputLimiter enforces Limit and MaxSize on results written to output
+type outputLimiter struct {
+	limit      int // max no of results (0 = no limit)
+	maxSize    int // max size of output in bytes
+	remaining  int // remaining size of output in bytes
+	count      int // no of results written
+	lineEndLen int // size of line ending written after each result
+}
+
+// newOutputLimiter returns outputLimiter with given limit, max size and size of line ending
+func newOutputLimiter(limit, maxSize, lineEndLen int) *outputLimiter {
+	return &outputLimiter{limit: limit, maxSize: maxSize, remaining: maxSize, lineEndLen: lineEndLen}
+}
+
+// check returns true if value whose output (excluding line ending) is of given size can be written.
+// if false is returned value is skipped and errStopGeneration is returned if no more values can be written
+func (l *outputLimiter) check(value string, size int) (bool, error) {
+	if l.limit > 0 && l.count == l.limit {
+		return false, errStopGeneration
+	}
+	if l.remaining <= 0 {
+		return false, errStopGeneration
+	}
+	if strings.HasPrefix(value, "-") {
+		return false, nil
+	}
+	size += l.lineEndLen
+	if size > l.maxSize {
+		// this entry can never fit in output, skip only this one
+		return false, nil
+	}
+	if size > l.remaining {
+		// size boundary reached
+		l.remaining = 0
+		return false, errStopGeneration
 	}
+	return true, nil
 }
 
-// EstimateCount estimates number of payloads that will be created
-// without actually executing/creating permutations
-func (m *Mutator) EstimateCount() int {
-	counter := 0
+// add records a written value of given size and returns errStopGeneration
+// as soon as limit is reached
+func (l *outputLimiter) add(size int) error {
+	// update remaining size after each write
+	l.remaining -= size
+	l.count++
+	if l.limit > 0 && l.count == l.limit {
+		// stop generating as soon as limit is reached
+		return errStopGeneration
+	}
+	return nil
+}
+
+// EstimateCount estimates number of payloads that will be created (including variants of
+// Typosquat, TypoGen and TLDSwap) without actually executing/creating permutations.
+// estimate saturates at math.MaxInt64 instead of overflowing
+func (m *Mutator) EstimateCount() int64 {
+	counter := m.estimateVariants()
 	for _, v := range m.Inputs {
-		varMap := getSampleMap(v.GetMap(), m.Options.Payloads)
+		varMap := m.sampleMap(v)
 		for _, pattern := range m.Options.Patterns {
-			if err := checkMissing(pattern, varMap); err == nil {
-				// if say patterns is {{sub}}.{{sub1}}-{{word}}.{{root}}
-				// and input domain is api.scanme.sh its clear that {{sub1}} here will be empty/missing
-				// in such cases `alterx` silently skips that pattern for that specific input
-				// this way user can have a long list of patterns but they are only used if all required data is given (much like self-contained templates)
-				statement := Replace(pattern, v.GetMap())
-				bin := unsafeToBytes(statement)
-				if m.maxkeyLenInBytes < len(bin) {
-					m.maxkeyLenInBytes = len(bin)
-				}
-				varsUsed := getAllVars(statement)
-				if len(varsUsed) == 0 {
-					counter += 1
-				} else {
-					tmpCounter := 1
-					for _, word := range varsUsed {
-						tmpCounter *= len(m.Options.Payloads[word])
-					}
-					counter += tmpCounter
-				}
+			// if say patterns is {{sub}}.{{sub1}}-{{word}}.{{root}}
+			// and input domain is api.scanme.sh its clear that {{sub1}} here will be empty/missing
+			// in such cases `alterx` silently skips that pattern for that specific input
+			// this way user can have a long list of patterns but they are only used if all required data is given (much like self-contained templates)
+			if count, ok := m.estimatePattern(pattern, v, varMap); ok {
+				counter = saturatingAdd(counter, count)
 			}
 		}
 	}
 	return counter
 }
 
+// estimatePattern estimates number of results of pattern for given input
+// it returns false if pattern cannot be evaluated for input
+func (m *Mutator) estimatePattern(pattern string, input *Input, varMap map[string]interface{}) (int64, bool) {
+	if err := checkMissing(pattern, varMap, m.delims); err != nil {
+		return 0, false
+	}
+	statement := m.replacePattern(pattern, input.GetMap())
+	bin := unsafeToBytes(statement)
+	if m.maxkeyLenInBytes < len(bin) {
+		m.maxkeyLenInBytes = len(bin)
+	}
+	// repeated variables share same value
+	varsUsed := sliceutil.Dedupe(getAllVars(statement, m.delims))
+	if len(varsUsed) == 0 {
+		return 1, true
+	}
+	var counter int64 = 1
+	for _, word := range varsUsed {
+		counter = saturatingMul(counter, int64(m.payloadLen(word)))
+	}
+	if m.Options.SampleSize > 0 && counter > int64(m.Options.SampleSize) {
+		counter = int64(m.Options.SampleSize)
+	}
+	return counter, true
+}
+
+// cappedValues estimates number of values skipped due to MaxPerInput i.e for each capped input
+// estimated results of each pattern minus values already generated by it. patterns that reached
+// LimitPerPattern are not counted since their remaining values are skipped regardless of MaxPerInput
+func (m *Mutator) cappedValues(inputCount map[*Input]int, generated map[*Input][]int, patternCount []int) int64 {
+	var skipped int64
+	for _, v := range m.Inputs {
+		if inputCount[v] < m.Options.MaxPerInput {
+			continue
+		}
+		varMap := m.sampleMap(v)
+		for index, pattern := range m.Options.Patterns {
+			if m.Options.LimitPerPattern > 0 && patternCount[index] >= m.Options.LimitPerPattern {
+				continue
+			}
+			count, ok := m.estimatePattern(pattern, v, varMap)
+			if !ok {
+				continue
+			}
+			if done := int64(generated[v][index]); count > done {
+				skipped = saturatingAdd(skipped, count-done)
+			}
+		}
+	}
+	return skipped
+}
+
 // DryRun executes payloads without storing and returns number of payloads created
 // this value is also stored in variable and can be accessed via getter `PayloadCount`
 func (m *Mutator) DryRun() int {
@@ -221,106 +897,449 @@ func (m *Mutator) DryRun() int {
 	return m.payloadCount
 }
 
-// clusterBomb calculates all payloads of clusterbomb attack and sends them to result channel
-func (m *Mutator) clusterBomb(template string, results chan string) {
+// clusterBomb calculates all payloads of clusterbomb attack and sends them to callback
+// it stops and returns error as soon as callback returns an error
+func (m *Mutator) clusterBomb(template string, callback func(value string) error) error {
 	// Early Exit: this is what saves clusterBomb from stackoverflows and reduces
 	// n*len(n) iterations and n recursions
-	varsUsed := getAllVars(template)
+	varsUsed := getAllVars(template, m.delims)
 	if len(varsUsed) == 0 {
 		// clusterBomb is not required
 		// just send existing template as result and exit
-		results <- template
-		return
+		return callback(template)
+	}
+	payloads := m.payloadSet(template)
+	for i := 0; i < payloads.Cap(); i++ {
+		if len(payloads.GetNth(i)) == 0 {
+			// no combination can be created if any of variables has no payloads
+			gologger.Debug().Msgf("no payloads left for `%v` in %v. skipping", payloads.KeyAtNth(i), template)
+			m.stats.SkippedPatterns++
+			return nil
+		}
 	}
+	// statement is compiled once and reused for all payload combinations
+	compiled := m.compile(template)
+	if m.Options.SampleSize > 0 {
+		// only generate random sample of all possible payloads
+		sampler := newClusterBombSampler(payloads, m.Options.SampleSize, m.rng)
+		for varMap, ok := sampler.Next(); ok; varMap, ok = sampler.Next() {
+			value, ok := m.expand(template, compiled, varMap)
+			if !ok {
+				continue
+			}
+			if err := callback(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// in clusterBomb attack no of payloads generated are
+	// len(first_set)*len(second_set)*len(third_set)....
+	var err error
+	callbackFunc := func(varMap map[string]interface{}) bool {
+		value, ok := m.expand(template, compiled, varMap)
+		if !ok {
+			return true
+		}
+		err = callback(value)
+		return err == nil
+	}
+	clusterBombUntil(payloads, callbackFunc, []string{})
+	return err
+}
+
+// expand replaces payload variables of template with given payload combination
+// compiled is precompiled template and is used unless template needs to be modified
+// it returns false if combination should be skipped
+func (m *Mutator) expand(template string, compiled *Template, varMap map[string]interface{}) (string, bool) {
+	if m.Options.DistinctPositional && hasEqualPositional(varMap) {
+		return "", false
+	}
+	collapsed := false
+	for k, v := range varMap {
+		if strings.HasSuffix(k, optionalMarker) && v == "" {
+			template = collapseOptional(template, k, m.delims)
+			collapsed = true
+		}
+	}
+	if compiled == nil || collapsed {
+		return m.replace(template, varMap), true
+	}
+	return ReplaceTemplate(compiled, varMap), true
+}
+
+// payloadIterator returns iterator over payload combinations of template
+// honoring Options.SampleSize
+func (m *Mutator) payloadIterator(template string) payloadIterator {
+	payloads := m.payloadSet(template)
+	if m.Options.SampleSize > 0 {
+		return newClusterBombSampler(payloads, m.Options.SampleSize, m.rng)
+	}
+	return newClusterBombIterator(payloads)
+}
+
+// payloadSet returns payloads of all variables used in template
+func (m *Mutator) payloadSet(template string) *IndexMap {
 	payloadSet := map[string][]string{}
+	tokens := getTokens(template, m.delims)
 	// instead of sending all payloads only send payloads that are used
 	// in template/statement
-	for _, v := range varsUsed {
+	for _, v := range getAllVars(template, m.delims) {
 		payloadSet[v] = []string{}
-		for _, word := range m.Options.Payloads[v] {
-			if !strings.Contains(template, word) {
-				// skip all words that are already present in template/sub , it is highly unlikely
+		// inline payloads are explicitly requested and always used as is
+		_, inline := m.inlinePayloads[strings.TrimSuffix(v, optionalMarker)]
+		// positional variables (ex: word:0) use payloads of base variable
+		for _, word := range m.payloadsOf(v) {
+			if m.Options.MaxPayloadsPerVar > 0 && len(payloadSet[v]) == m.Options.MaxPayloadsPerVar {
+				break
+			}
+			if _, ok := tokens[word]; !ok || inline || word == "" || m.Options.AllowDuplicateTokens {
+				// skip all words that are already present as a token in template/sub , it is highly unlikely
 				// we will ever find api-api.example.com
 				payloadSet[v] = append(payloadSet[v], word)
 			}
 		}
 	}
-	payloads := NewIndexMap(payloadSet)
-	// in clusterBomb attack no of payloads generated are
-	// len(first_set)*len(second_set)*len(third_set)....
-	callbackFunc := func(varMap map[string]interface{}) {
-		results <- Replace(template, varMap)
-	}
-	ClusterBomb(payloads, callbackFunc, []string{})
+	return NewIndexMap(payloadSet)
 }
 
 // prepares input and patterns and calculates estimations
 func (m *Mutator) prepareInputs() error {
-	var errors []string
 	// prepare input
-	var allInputs []*Input
-	for _, v := range m.Options.Domains {
-		i, err := NewInput(v)
+	m.inputErrors = nil
+	inputs, errors := m.parseInputs(m.Options.Domains)
+	m.Inputs = inputs
+	if len(errors) > 0 {
+		if m.Options.StrictInput {
+			return fmt.Errorf("errors found when preparing inputs got: %v", strings.Join(errors, " : "))
+		}
+		gologger.Warning().Msgf("errors found when preparing inputs got: %v : skipping errored inputs", strings.Join(errors, " : "))
+	}
+	return nil
+}
+
+// parseInputs parses given domains and returns valid inputs along with errors of
+// inputs that failed to parse (also recorded in InputErrors)
+func (m *Mutator) parseInputs(domains []string) ([]*Input, []string) {
+	var errors []string
+	var inputs []*Input
+	for _, v := range domains {
+		i, err := NewInputWithRoot(v, m.Options.DefaultRoot)
 		if err != nil {
 			errors = append(errors, err.Error())
+			m.inputErrors = append(m.inputErrors, InputError{Raw: v, Err: err})
 			continue
 		}
-		allInputs = append(allInputs, i)
+		inputs = append(inputs, i)
+	}
+	return inputs, errors
+}
+
+// AddDomains parses and appends given domains to inputs of existing Mutator
+// (payloads are enriched with new inputs if Enrich is enabled). valid domains are
+// always added and error is returned if any of domains failed to parse.
+// it must not be called while Mutator is executing
+func (m *Mutator) AddDomains(domains ...string) error {
+	inputs, errors := m.parseInputs(domains)
+	m.Inputs = append(m.Inputs, inputs...)
+	m.Options.Domains = append(m.Options.Domains, domains...)
+	if m.Options.Enrich && len(inputs) > 0 {
+		m.enrichPayloads(inputs)
+		m.sortPayloadsByWeight()
 	}
-	m.Inputs = allInputs
 	if len(errors) > 0 {
-		gologger.Warning().Msgf("errors found when preparing inputs got: %v : skipping errored inputs", strings.Join(errors, " : "))
+		return fmt.Errorf("errors found when adding domains got: %v", strings.Join(errors, " : "))
 	}
 	return nil
 }
 
+// loadKnownHosts loads hosts from KnownHostsFile in same form as results are deduped
+func (m *Mutator) loadKnownHosts() error {
+	m.knownHosts = nil
+	if m.Options.KnownHostsFile == "" {
+		return nil
+	}
+	hosts, err := ReadLinesFromFile(m.Options.KnownHostsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read known hosts file %v got %v", m.Options.KnownHostsFile, err)
+	}
+	m.knownHosts = make(map[string]struct{}, len(hosts))
+	for _, v := range hosts {
+		m.knownHosts[toASCIIHostname(normalizeHostname(v))] = struct{}{}
+	}
+	return nil
+}
+
+// Close releases inputs and caches owned by Mutator so that they can be garbage collected.
+// Options given to New are not modified. Mutator must not be used after Close. calling Close more than once is safe
+func (m *Mutator) Close() {
+	m.Inputs = nil
+	m.inputErrors = nil
+	m.inlinePayloads = nil
+	m.templates = nil
+	m.knownHosts = nil
+	m.coverage = nil
+}
+
+// Reset clears counters, stats and coverage of previous execution so that Mutator
+// can be reused for a clean run. parsed inputs, patterns and payloads are kept as is
+// i.e changes to Options after New are not applied and require a new Mutator
+func (m *Mutator) Reset() {
+	m.payloadCount = 0
+	m.stats = RunStats{}
+	m.coverage = nil
+}
+
+// Patterns returns copy of patterns used by Mutator
+func (m *Mutator) Patterns() []string {
+	patterns := make([]string, len(m.Options.Patterns))
+	copy(patterns, m.Options.Patterns)
+	return patterns
+}
+
+// Payloads returns copy of payloads used by Mutator (after dedupe, exclusion and enrichment)
+func (m *Mutator) Payloads() map[string][]string {
+	payloads := make(map[string][]string, len(m.Options.Payloads))
+	for k, v := range m.Options.Payloads {
+		payloads[k] = append([]string(nil), v...)
+	}
+	return payloads
+}
+
+// InputErrors returns inputs that were skipped along with the reason
+func (m *Mutator) InputErrors() []InputError {
+	return m.inputErrors
+}
+
 // validates all patterns by compiling them
 func (m *Mutator) validatePatterns() error {
+	m.inlinePayloads = map[string][]string{}
+	m.templates = map[string]*Template{}
 	for _, v := range m.Options.Patterns {
 		// check if all placeholders are correctly used and are valid
-		if _, err := fasttemplate.NewTemplate(v, ParenthesisOpen, ParenthesisClose); err != nil {
+		tpl, err := NewTemplate(v, m.delims.open, m.delims.close)
+		if err != nil {
 			return err
 		}
+		m.templates[v] = tpl
+		// expand inline payloads (ex: {{number:01-50}}, {{(dev|prod)}}) used in pattern
+		for _, name := range getAllVars(v, m.delims) {
+			values, ok, err := parseInlinePayload(strings.TrimSuffix(name, optionalMarker))
+			if err != nil {
+				return fmt.Errorf("invalid pattern %v: %v", v, err)
+			}
+			if ok {
+				m.inlinePayloads[strings.TrimSuffix(name, optionalMarker)] = values
+			}
+		}
 	}
 	return nil
 }
 
+// warnEmptyPayloads warns about patterns referencing payloads that are empty
+// (ex: all words were removed by ExcludeWords) since such patterns never produce any result
+func (m *Mutator) warnEmptyPayloads() {
+	for _, pattern := range m.Options.Patterns {
+		for _, v := range sliceutil.Dedupe(getAllVars(pattern, m.delims)) {
+			if _, ok := m.inlinePayloads[strings.TrimSuffix(v, optionalMarker)]; ok {
+				continue
+			}
+			if values, ok := m.Options.Payloads[baseVarName(v)]; ok && len(values) == 0 {
+				gologger.Warning().Msgf("payload `%v` used in pattern %v is empty. pattern will be skipped", baseVarName(v), pattern)
+			}
+		}
+	}
+}
+
+// validatePatternVars checks if all variables used in patterns are either builtin
+// input variables or have a corresponding payload and returns error listing unknown variables
+func (m *Mutator) validatePatternVars() error {
+	var errs []string
+	for _, pattern := range m.Options.Patterns {
+		var unknown []string
+		for _, v := range sliceutil.Dedupe(getAllVars(pattern, m.delims)) {
+			if _, ok := m.inlinePayloads[strings.TrimSuffix(v, optionalMarker)]; ok {
+				continue
+			}
+			name := baseVarName(v)
+			if _, ok := m.Options.Payloads[name]; ok || isBuiltinVar(name) {
+				continue
+			}
+			unknown = append(unknown, name)
+		}
+		if len(unknown) > 0 {
+			errs = append(errs, fmt.Sprintf("%v (unknown variables: %v)", pattern, strings.Join(unknown, ",")))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("patterns reference variables without payloads: %v", strings.Join(errs, " : "))
+	}
+	return nil
+}
+
+// supported values of Options.EnrichMode
+const (
+	// EnrichWords only enriches word payloads
+	EnrichWords = "words"
+	// EnrichNumbers only enriches number payloads
+	EnrichNumbers = "numbers"
+	// EnrichBoth enriches both word and number payloads
+	EnrichBoth = "both"
+)
+
+// defaultEnrichMinWordLen is default value of Options.EnrichMinWordLen
+const defaultEnrichMinWordLen = 3
+
+// maxNumberNeighbors is max no of neighbors of extracted numbers added during enrichment (see Options.NumberNeighbors)
+const maxNumberNeighbors = 1000
+
 // enrichPayloads extract possible words and adds them to default wordlist
-func (m *Mutator) enrichPayloads() {
+func (m *Mutator) enrichPayloads(inputs []*Input) {
 	var temp bytes.Buffer
-	for _, v := range m.Inputs {
+	for _, v := range inputs {
 		temp.WriteString(v.Sub + " ")
 		if len(v.MultiLevel) > 0 {
 			temp.WriteString(strings.Join(v.MultiLevel, " "))
 		}
 	}
 	numbers := extractNumbers.FindAllString(temp.String(), -1)
+	if m.Options.NumberNeighbors > 0 {
+		numbers = append(numbers, numberNeighbors(sliceutil.Dedupe(numbers), m.Options.NumberNeighbors, maxNumberNeighbors)...)
+	}
 	extraWords := extractWords.FindAllString(temp.String(), -1)
-	extraWordsOnly := extractWordsOnly.FindAllString(temp.String(), -1)
+	var extraWordsOnly []string
+	for _, v := range extractWordsOnly.FindAllString(temp.String(), -1) {
+		if len(v) >= m.Options.EnrichMinWordLen {
+			extraWordsOnly = append(extraWordsOnly, v)
+		}
+	}
 	if len(extraWordsOnly) > 0 {
 		extraWords = append(extraWords, extraWordsOnly...)
 		extraWords = sliceutil.Dedupe(extraWords)
 	}
 
-	if len(m.Options.Payloads["word"]) > 0 {
+	enrichWords := m.Options.EnrichMode != EnrichNumbers
+	enrichNumbers := m.Options.EnrichMode != EnrichWords
+	if enrichWords && len(m.Options.Payloads["word"]) > 0 {
 		extraWords = append(extraWords, m.Options.Payloads["word"]...)
-		m.Options.Payloads["word"] = sliceutil.Dedupe(extraWords)
+		m.Options.Payloads["word"] = m.removeExcluded(sliceutil.Dedupe(extraWords))
 	}
-	if len(m.Options.Payloads["number"]) > 0 {
+	if enrichNumbers && len(m.Options.Payloads["number"]) > 0 {
 		numbers = append(numbers, m.Options.Payloads["number"]...)
-		m.Options.Payloads["number"] = sliceutil.Dedupe(numbers)
+		m.Options.Payloads["number"] = m.removeExcluded(sliceutil.Dedupe(numbers))
+	}
+}
+
+// sortPayloadsByWeight orders payloads of variables present in Options.PayloadWeights
+// by weight in descending order (payloads without weight are moved to end)
+func (m *Mutator) sortPayloadsByWeight() {
+	for k, weights := range m.Options.PayloadWeights {
+		payloads, ok := m.Options.Payloads[k]
+		if !ok || len(weights) == 0 {
+			continue
+		}
+		sorted := make([]string, len(payloads))
+		copy(sorted, payloads)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return weights[sorted[i]] > weights[sorted[j]]
+		})
+		m.Options.Payloads[k] = sorted
+	}
+}
+
+// payloadsOf returns payloads of given variable
+// positional variables (ex: word:0) use payloads of base variable
+// optional variables (ex: word?) additionally expand to empty string
+func (m *Mutator) payloadsOf(name string) []string {
+	values, ok := m.inlinePayloads[strings.TrimSuffix(name, optionalMarker)]
+	if !ok {
+		values = m.Options.Payloads[baseVarName(name)]
+	}
+	if strings.HasSuffix(name, optionalMarker) && len(values) > 0 {
+		return append([]string{""}, values...)
+	}
+	return values
+}
+
+// sampleMap returns a sample map containing input, payload and inline payload variables
+func (m *Mutator) sampleMap(input *Input) map[string]interface{} {
+	varMap := getSampleMap(input.GetMap(), m.Options.Payloads)
+	for k := range m.inlinePayloads {
+		varMap[k] = "temp"
+	}
+	return varMap
+}
+
+// payloadLen returns number of payloads used for given variable
+func (m *Mutator) payloadLen(key string) int {
+	count := len(m.payloadsOf(key))
+	if m.Options.MaxPayloadsPerVar > 0 && count > m.Options.MaxPayloadsPerVar {
+		return m.Options.MaxPayloadsPerVar
 	}
+	return count
+}
+
+// removeExcluded removes all words present in Options.ExcludeWords from given words
+func (m *Mutator) removeExcluded(words []string) []string {
+	if len(m.Options.ExcludeWords) == 0 {
+		return words
+	}
+	excluded := map[string]struct{}{}
+	for _, v := range m.Options.ExcludeWords {
+		if m.Options.ExcludeWordsIgnoreCase {
+			v = strings.ToLower(v)
+		}
+		excluded[v] = struct{}{}
+	}
+	filtered := make([]string, 0, len(words))
+	for _, word := range words {
+		key := word
+		if m.Options.ExcludeWordsIgnoreCase {
+			key = strings.ToLower(word)
+		}
+		if _, ok := excluded[key]; !ok {
+			filtered = append(filtered, word)
+		}
+	}
+	return filtered
+}
+
+// replace replaces variables in template with values using configured delimiters
+func (m *Mutator) replace(template string, values map[string]interface{}) string {
+	return ReplaceWithDelims(template, m.delims.open, m.delims.close, values)
+}
+
+// replacePattern replaces variables of pattern with values using precompiled pattern
+func (m *Mutator) replacePattern(pattern string, values map[string]interface{}) string {
+	if tpl, ok := m.templates[pattern]; ok {
+		return ReplaceTemplate(tpl, values)
+	}
+	return m.replace(pattern, values)
+}
+
+// compile returns compiled template or nil if template cannot be compiled
+func (m *Mutator) compile(template string) *Template {
+	tpl, err := NewTemplate(template, m.delims.open, m.delims.close)
+	if err != nil {
+		return nil
+	}
+	return tpl
+}
+
+// dedupeEnabled returns true if results of this instance should be deduped
+func (m *Mutator) dedupeEnabled() bool {
+	return !m.Options.DisableDedupe && DedupeResults
 }
 
 // PayloadCount returns total estimated payloads count
 func (m *Mutator) PayloadCount() int {
 	if m.payloadCount == 0 {
-		return m.EstimateCount()
+		return clampInt(m.EstimateCount())
 	}
 	return m.payloadCount
 }
 
 // Time returns time taken to create permutations in seconds
 func (m *Mutator) Time() string {
-	return fmt.Sprintf("%.4fs", m.timeTaken.Seconds())
+	return fmt.Sprintf("%.4fs", m.stats.TimeTaken.Seconds())
 }
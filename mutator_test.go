@@ -2,9 +2,18 @@ package alterx
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -49,3 +58,1318 @@ func TestMutatorResults(t *testing.T) {
 	count := strings.Split(strings.TrimSpace(buff.String()), "\n")
 	require.EqualValues(t, 80, len(count), buff.String())
 }
+
+func TestMutatorDelimiters(t *testing.T) {
+	opts := &Options{
+		Domains:    []string{"api.scanme.sh"},
+		Patterns:   []string{"<<sub>>-<<word>>.<<root>>"},
+		Payloads:   map[string][]string{"word": {"dev", "{{prod}}"}},
+		MaxSize:    math.MaxInt,
+		OpenDelim:  "<<",
+		CloseDelim: ">>",
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"api-dev.scanme.sh", "api-{{prod}}.scanme.sh"}, strings.Fields(buff.String()))
+
+	opts.CloseDelim = "<<"
+	_, err = New(opts)
+	require.NotNil(t, err)
+
+	// empty delimiters are treated as defaults
+	require.Nil(t, (&Options{OpenDelim: "<<"}).Validate())
+	require.NotNil(t, (&Options{CloseDelim: ParenthesisOpen}).Validate())
+
+	// default patterns are rewritten to use custom delimiters
+	m, err = New(&Options{Domains: []string{"api.scanme.sh"}, OpenDelim: "<<", CloseDelim: ">>", Limit: 50})
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 50)
+	for _, v := range results {
+		require.NotContains(t, v, "<<")
+		require.NotContains(t, v, ">>")
+		require.NotContains(t, v, ParenthesisOpen)
+	}
+}
+
+func TestMutatorExecuteCancel(t *testing.T) {
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{sub}}-{{number:0-9999}}.{{suffix}}"},
+	})
+	require.Nil(t, err)
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	results := m.Execute(ctx)
+	<-results
+	// caller stops reading after cancel and no goroutine is leaked
+	cancel()
+	for i := 0; i < 500 && runtime.NumGoroutine() > goroutines; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestMutatorExecuteWithCallback(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh", "chaos.scanme.sh", "nuclei.scanme.sh", "cloud.nuclei.scanme.sh"},
+		Patterns: testConfig.Patterns,
+		Payloads: testConfig.Payloads,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+
+	var results []string
+	err = m.ExecuteWithCallback(context.Background(), func(subdomain string) error {
+		results = append(results, subdomain)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Len(t, results, 80)
+
+	errStop := errors.New("stop")
+	count := 0
+	err = m.ExecuteWithCallback(context.Background(), func(subdomain string) error {
+		count++
+		if count == 10 {
+			return errStop
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, 10, count)
+}
+
+func TestMutatorExcludeWords(t *testing.T) {
+	opts := &Options{
+		Domains:                []string{"api.scanme.sh", "codename.scanme.sh"},
+		Patterns:               []string{"{{word}}.{{root}}"},
+		Payloads:               map[string][]string{"word": {"dev", "Codename", "prod"}},
+		ExcludeWords:           []string{"codename", "prod"},
+		ExcludeWordsIgnoreCase: true,
+		Enrich:                 true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"dev", "api"}, m.Options.Payloads["word"])
+
+	opts = &Options{
+		Domains:      []string{"api.scanme.sh"},
+		Patterns:     []string{"{{word}}.{{root}}"},
+		Payloads:     map[string][]string{"word": {"dev", "Codename", "prod"}},
+		ExcludeWords: []string{"codename"},
+	}
+	m, err = New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"dev", "Codename", "prod"}, m.Options.Payloads["word"])
+}
+
+func TestMutatorNumberNeighbors(t *testing.T) {
+	opts := &Options{
+		Domains:         []string{"node17.scanme.sh", "node42.scanme.sh", "db07.scanme.sh"},
+		Patterns:        []string{"{{word}}{{number}}.{{root}}"},
+		Payloads:        map[string][]string{"word": {"node"}, "number": {"1"}},
+		Enrich:          true,
+		NumberNeighbors: 1,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"16", "17", "18", "41", "42", "43", "06", "07", "08", "1"}, m.Options.Payloads["number"])
+
+	opts.Payloads = map[string][]string{"word": {"node"}, "number": {"1"}}
+	opts.NumberNeighbors = 0
+	m, err = New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"17", "42", "07", "1"}, m.Options.Payloads["number"])
+
+	// added neighbors are capped but observed numbers are always kept and
+	// closest neighbors of all numbers are added first
+	opts.Domains = []string{"node900.scanme.sh", "node5000.scanme.sh"}
+	opts.Payloads = map[string][]string{"word": {"node"}, "number": {"1"}}
+	opts.NumberNeighbors = 600
+	m, err = New(opts)
+	require.Nil(t, err)
+	numbers := m.Options.Payloads["number"]
+	require.Len(t, numbers, 2+maxNumberNeighbors+1)
+	for _, v := range []string{"900", "5000", "1", "899", "901", "4999", "5001", "650", "1150", "4750", "5250"} {
+		require.Contains(t, numbers, v)
+	}
+	for _, v := range []string{"649", "1151", "4749", "5251"} {
+		require.NotContains(t, numbers, v)
+	}
+
+	// extracted numbers are not capped without neighbors
+	opts.Domains = nil
+	for i := 0; i < 1500; i++ {
+		opts.Domains = append(opts.Domains, fmt.Sprintf("node%v.scanme.sh", i))
+	}
+	opts.Payloads = map[string][]string{"word": {"node"}, "number": {"1"}}
+	opts.NumberNeighbors = 0
+	m, err = New(opts)
+	require.Nil(t, err)
+	require.Len(t, m.Options.Payloads["number"], 1500)
+
+	opts.NumberNeighbors = -1
+	_, err = New(opts)
+	require.NotNil(t, err)
+}
+
+func TestMutatorEnrichMinWordLen(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"qa1.scanme.sh", "api2.scanme.sh"},
+		Patterns: []string{"{{word}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+		Enrich:   true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"qa1", "api2", "api", "dev"}, m.Options.Payloads["word"])
+
+	opts.Payloads = map[string][]string{"word": {"dev"}}
+	opts.EnrichMinWordLen = 2
+	m, err = New(opts)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"qa1", "api2", "qa", "api", "dev"}, m.Options.Payloads["word"])
+}
+
+func TestMutatorEnrichMode(t *testing.T) {
+	testcases := []struct {
+		mode    string
+		words   []string
+		numbers []string
+	}{
+		{mode: "", words: []string{"dev", "node17", "node"}, numbers: []string{"1", "17"}},
+		{mode: EnrichBoth, words: []string{"dev", "node17", "node"}, numbers: []string{"1", "17"}},
+		{mode: EnrichWords, words: []string{"dev", "node17", "node"}, numbers: []string{"1"}},
+		{mode: EnrichNumbers, words: []string{"dev"}, numbers: []string{"1", "17"}},
+	}
+	for _, v := range testcases {
+		m, err := New(&Options{
+			Domains:    []string{"node17.scanme.sh"},
+			Patterns:   []string{"{{word}}{{number}}.{{root}}"},
+			Payloads:   map[string][]string{"word": {"dev"}, "number": {"1"}},
+			Enrich:     true,
+			EnrichMode: v.mode,
+		})
+		require.Nil(t, err)
+		require.ElementsMatchf(t, v.words, m.Options.Payloads["word"], "mode %v", v.mode)
+		require.ElementsMatchf(t, v.numbers, m.Options.Payloads["number"], "mode %v", v.mode)
+	}
+
+	_, err := New(&Options{Domains: []string{"node17.scanme.sh"}, Enrich: true, EnrichMode: "all"})
+	require.NotNil(t, err)
+	require.Nil(t, (&Options{Enrich: true}).Validate())
+}
+
+func TestMutatorRegexFilter(t *testing.T) {
+	opts := &Options{
+		Domains:      []string{"api.scanme.sh"},
+		Patterns:     []string{"{{word}}-{{sub}}.{{root}}"},
+		Payloads:     map[string][]string{"word": {"dev", "test", "prod", "stage"}},
+		MaxSize:      math.MaxInt,
+		IncludeRegex: `^(dev|test|prod)-`,
+		ExcludeRegex: `test`,
+		Limit:        2,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"dev-api.scanme.sh", "prod-api.scanme.sh"}, strings.Fields(buff.String()))
+
+	opts.IncludeRegex = "("
+	_, err = New(opts)
+	require.NotNil(t, err)
+}
+
+func TestMutatorLimit(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh", "chaos.scanme.sh", "nuclei.scanme.sh", "cloud.nuclei.scanme.sh"},
+		Patterns: testConfig.Patterns,
+		Payloads: testConfig.Payloads,
+		MaxSize:  math.MaxInt,
+		Limit:    5,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Len(t, strings.Fields(buff.String()), 5)
+	require.Equal(t, 5, m.PayloadCount())
+}
+
+func TestMutatorMaxSize(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev", "averyveryverylongword", "prod", "qa"}},
+		// fits dev.scanme.sh\n (14 bytes) and prod.scanme.sh\n (15 bytes) but not qa.scanme.sh\n
+		MaxSize:       30,
+		DisableDedupe: true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Equal(t, "dev.scanme.sh\nprod.scanme.sh\n", buff.String())
+
+	// oversized single line is skipped and rest of output is still written
+	opts.MaxSize = 20
+	opts.Payloads = map[string][]string{"word": {"averyveryverylongword", "dev"}}
+	m, err = New(opts)
+	require.Nil(t, err)
+	buff.Reset()
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Equal(t, "dev.scanme.sh\n", buff.String())
+}
+
+func TestMutatorRunStructured(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod", "stage"}},
+		// fits default:dev.scanme.sh\r\n (23 bytes) but not default:prod.scanme.sh\r\n (24 bytes)
+		MaxSize:    40,
+		LineEnding: "\r\n",
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.RunStructured(context.Background(), &buff, func(res Result) ([]byte, error) {
+		return []byte(res.Source + ":" + res.Subdomain), nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, "default:dev.scanme.sh\r\n", buff.String())
+	require.Equal(t, 1, stats.Written)
+
+	_, err = m.RunStructured(context.Background(), &buff, nil)
+	require.NotNil(t, err)
+}
+
+func TestMutatorLineEnding(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod"}},
+		// fits dev.scanme.sh\r\n (15 bytes) but not prod.scanme.sh\r\n (16 bytes)
+		MaxSize:    30,
+		LineEnding: "\r\n",
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Equal(t, "dev.scanme.sh\r\n", buff.String())
+
+	opts.LineEnding = "\r"
+	_, err = New(opts)
+	require.NotNil(t, err)
+	require.NotNil(t, (&Options{LineEnding: "\r"}).Validate())
+	// empty line ending is treated as default
+	require.Nil(t, (&Options{}).Validate())
+}
+
+func TestMutatorMaxPayloadsPerVar(t *testing.T) {
+	opts := &Options{
+		Domains:           []string{"api.scanme.sh"},
+		Patterns:          []string{"{{word}}-{{number}}.{{root}}"},
+		Payloads:          map[string][]string{"word": {"dev", "prod", "stage", "qa"}, "number": {"1", "2", "3"}},
+		MaxSize:           math.MaxInt,
+		MaxPayloadsPerVar: 2,
+		PayloadWeights:    map[string]map[string]int{"word": {"qa": 10, "stage": 5}},
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.EqualValues(t, 4, m.EstimateCount())
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"qa-1.scanme.sh", "qa-2.scanme.sh", "stage-1.scanme.sh", "stage-2.scanme.sh"}, strings.Fields(buff.String()))
+}
+
+func TestMutatorNormalizeOutput(t *testing.T) {
+	opts := &Options{
+		Domains:         []string{"api.scanme.sh"},
+		Patterns:        []string{"{{word}}.{{root}}", "{{word}}.{{root}}."},
+		Payloads:        map[string][]string{"word": {"DEV", "dev", "Dev"}},
+		MaxSize:         math.MaxInt,
+		NormalizeOutput: true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Equal(t, []string{"dev.scanme.sh"}, strings.Fields(buff.String()))
+}
+
+func TestMutatorIDNOutput(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"café.example.com"},
+		Patterns: []string{"{{word}}.{{sub}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev", "über"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	require.ElementsMatch(t, []string{"dev.xn--caf-dma.example.com", "xn--ber-goa.xn--caf-dma.example.com"}, strings.Fields(buff.String()))
+
+	opts.UnicodeOutput = true
+	m, err = New(opts)
+	require.Nil(t, err)
+	buff.Reset()
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	require.ElementsMatch(t, []string{"dev.café.example.com", "über.café.example.com"}, strings.Fields(buff.String()))
+}
+
+func TestMutatorRunStats(t *testing.T) {
+	opts := &Options{
+		Domains:      []string{"api.scanme.sh", "api.dev.scanme.sh"},
+		Patterns:     []string{"{{word}}.{{root}}", "{{sub1}}-{{word}}.{{root}}"},
+		Payloads:     map[string][]string{"word": {"dev", "prod", "stage"}},
+		MaxSize:      math.MaxInt,
+		ExcludeRegex: "^stage",
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	require.Equal(t, 8, stats.Generated)
+	require.Equal(t, 3, stats.Duplicates)
+	require.Equal(t, 1, stats.Filtered)
+	require.Equal(t, 4, stats.Written)
+	require.Equal(t, 1, stats.SkippedPatterns)
+	require.Len(t, strings.Fields(buff.String()), stats.Written)
+}
+
+func TestMutatorProgress(t *testing.T) {
+	for _, tc := range []struct {
+		interval int
+		calls    []int
+	}{
+		{interval: 2, calls: []int{2, 3}},
+		{interval: -1, calls: []int{3}},
+	} {
+		var calls []int
+		m, err := New(&Options{
+			Domains:          []string{"api.scanme.sh"},
+			Patterns:         []string{"{{word}}-{{sub}}.{{suffix}}"},
+			Payloads:         map[string][]string{"word": {"dev", "prod", "stage"}},
+			MaxSize:          math.MaxInt,
+			ProgressInterval: tc.interval,
+			OnProgress: func(done, total int) {
+				require.Equal(t, 3, total)
+				calls = append(calls, done)
+			},
+		})
+		require.Nil(t, err)
+		_, err = m.Run(context.Background(), io.Discard)
+		require.Nil(t, err)
+		require.Equal(t, tc.calls, calls)
+	}
+
+	// unset interval uses default
+	m, err := New(&Options{Domains: []string{"api.scanme.sh"}})
+	require.Nil(t, err)
+	require.Equal(t, defaultProgressInterval, m.Options.ProgressInterval)
+}
+
+func TestMutatorInputErrors(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh", "prod.*.scanme.sh", "https://127.0.0.1"},
+		Patterns: testConfig.Patterns,
+		Payloads: testConfig.Payloads,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.Len(t, m.Inputs, 1)
+	inputErrors := m.InputErrors()
+	require.Len(t, inputErrors, 2)
+	require.Equal(t, "prod.*.scanme.sh", inputErrors[0].Raw)
+	require.Equal(t, "https://127.0.0.1", inputErrors[1].Raw)
+
+	opts.StrictInput = true
+	_, err = New(opts)
+	require.NotNil(t, err)
+}
+
+func TestMutatorDefaultRoot(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api-dev", "internal"},
+		Patterns: []string{"{{sub}}-{{word}}.{{suffix}}", "{{word}}-{{sub}}"},
+		Payloads: map[string][]string{"word": {"qa"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"qa-api-dev", "qa-internal"}, results)
+
+	opts.DefaultRoot = "corp.local"
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err = m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"api-dev-qa.corp.local", "internal-qa.corp.local", "qa-api-dev", "qa-internal"}, results)
+
+	opts.DefaultRoot = "corp..local"
+	_, err = New(opts)
+	require.NotNil(t, err)
+}
+
+func TestMutatorDNSValid(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev", strings.Repeat("a", 64), "in valid", "bad_char", "-dash", "prod"}},
+		MaxSize:  math.MaxInt,
+		DNSValid: true,
+		Limit:    2,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	require.Equal(t, []string{"dev.scanme.sh", "prod.scanme.sh"}, strings.Fields(buff.String()))
+}
+
+func TestMutatorLimitPerPattern(t *testing.T) {
+	opts := &Options{
+		Domains:         []string{"api.scanme.sh", "chaos.scanme.sh", "nuclei.scanme.sh", "cloud.nuclei.scanme.sh"},
+		Patterns:        testConfig.Patterns,
+		Payloads:        testConfig.Payloads,
+		MaxSize:         math.MaxInt,
+		LimitPerPattern: 3,
+		Limit:           10,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var results []string
+	err = m.ExecuteWithCallback(context.Background(), func(subdomain string) error {
+		results = append(results, subdomain)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Len(t, results, len(opts.Patterns)*opts.LimitPerPattern)
+
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	require.Len(t, strings.Fields(buff.String()), 10)
+}
+
+func TestMutatorMaxPerInput(t *testing.T) {
+	for _, interleave := range []bool{false, true} {
+		m, err := New(&Options{
+			Domains:     []string{"api.scanme.sh", "cdn.scanme.sh", "www.scanme.sh"},
+			Patterns:    []string{"{{word}}-{{sub}}.{{suffix}}", "{{sub}}-{{word}}.{{suffix}}"},
+			Payloads:    map[string][]string{"word": {"dev", "prod", "stage"}},
+			MaxPerInput: 4,
+			Interleave:  interleave,
+			MaxSize:     math.MaxInt,
+		})
+		require.Nil(t, err)
+		var buff bytes.Buffer
+		stats, err := m.Run(context.Background(), &buff)
+		require.Nil(t, err)
+		results := strings.Fields(buff.String())
+		require.Len(t, results, 12)
+		for _, sub := range []string{"api", "cdn", "www"} {
+			count := 0
+			for _, v := range results {
+				if strings.Contains(v, sub) {
+					count++
+				}
+			}
+			require.Equalf(t, 4, count, "interleave %v: results of %v", interleave, sub)
+		}
+		require.Equal(t, 3, stats.CappedInputs)
+		// 6 permutations of each input of which 4 are written
+		require.EqualValues(t, 6, stats.CappedValues)
+	}
+}
+
+func TestMutatorInterleave(t *testing.T) {
+	opts := &Options{
+		Domains:    []string{"api.scanme.sh", "chaos.scanme.sh"},
+		Patterns:   testConfig.Patterns,
+		Payloads:   testConfig.Payloads,
+		MaxSize:    math.MaxInt,
+		Interleave: true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var results []Result
+	for res := range m.ExecuteStructured(context.Background()) {
+		results = append(results, res)
+	}
+	require.Len(t, results, 40)
+	// first cycle contains one result of each (input, pattern) pair
+	var patterns []string
+	for _, res := range results[:8] {
+		patterns = append(patterns, res.Pattern)
+	}
+	require.Equal(t, append(testConfig.Patterns, testConfig.Patterns...), patterns)
+
+	opts.Interleave = false
+	m, err = New(opts)
+	require.Nil(t, err)
+	var sequential []string
+	for res := range m.ExecuteStructured(context.Background()) {
+		sequential = append(sequential, res.Subdomain)
+	}
+	var interleaved []string
+	for _, res := range results {
+		interleaved = append(interleaved, res.Subdomain)
+	}
+	require.ElementsMatch(t, sequential, interleaved)
+}
+
+func TestMutatorSampleSize(t *testing.T) {
+	words := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		words = append(words, fmt.Sprintf("w%v", i))
+	}
+	execute := func(seed int64) []string {
+		opts := &Options{
+			Domains:    []string{"api.scanme.sh"},
+			Patterns:   []string{"{{word}}-{{word2}}-{{word3}}.{{root}}"},
+			Payloads:   map[string][]string{"word": words, "word2": words, "word3": words},
+			MaxSize:    math.MaxInt,
+			SampleSize: 50,
+			Seed:       seed,
+		}
+		m, err := New(opts)
+		require.Nil(t, err)
+		require.EqualValues(t, 50, m.EstimateCount())
+		var buff bytes.Buffer
+		require.Nil(t, m.ExecuteWithWriter(&buff))
+		return strings.Fields(buff.String())
+	}
+	results := execute(42)
+	require.Len(t, results, 50)
+	require.Equal(t, results, execute(42))
+	require.NotEqual(t, results, execute(7))
+
+	// same seed produces same output in every run of same mutator
+	m, err := New(&Options{
+		Domains:    []string{"api.scanme.sh"},
+		Patterns:   []string{"{{word}}-{{word2}}.{{root}}"},
+		Payloads:   map[string][]string{"word": words, "word2": words},
+		SampleSize: 50,
+		Shuffle:    true,
+		Seed:       42,
+	})
+	require.Nil(t, err)
+	first, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	second, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, first, 50)
+	require.Equal(t, first, second)
+}
+
+func TestMutatorPositionalVars(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"scanme.sh"},
+		Patterns: []string{"{{word:0}}-{{word:1}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"api", "dev", "prod"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.EqualValues(t, 9, m.EstimateCount())
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	results := strings.Fields(buff.String())
+	require.Len(t, results, 9)
+	require.Contains(t, results, "api-dev.scanme.sh")
+	require.Contains(t, results, "dev-api.scanme.sh")
+
+	opts.DistinctPositional = true
+	m, err = New(opts)
+	require.Nil(t, err)
+	buff.Reset()
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	results = strings.Fields(buff.String())
+	require.Len(t, results, 6)
+	require.NotContains(t, results, "api-api.scanme.sh")
+}
+
+func TestMutatorInlineRange(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{sub}}-{{number:08-12}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.EqualValues(t, 5, m.EstimateCount())
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	results := strings.Fields(buff.String())
+	require.ElementsMatch(t, []string{"api-08.scanme.sh", "api-09.scanme.sh", "api-10.scanme.sh", "api-11.scanme.sh", "api-12.scanme.sh"}, results)
+
+	opts.Patterns = []string{"{{sub}}-{{number:5-1}}.{{suffix}}"}
+	_, err = New(opts)
+	require.NotNil(t, err)
+
+	// oversized ranges are rejected instead of being expanded in memory
+	for _, pattern := range []string{"{{sub}}-{{n:0-9223372036854775807}}.{{suffix}}", "{{sub}}-{{n:0-100000}}.{{suffix}}"} {
+		opts.Patterns = []string{pattern}
+		_, err = New(opts)
+		require.NotNilf(t, err, "expected error for %v", pattern)
+	}
+	opts.Patterns = []string{"{{sub}}-{{n:0-99999}}.{{suffix}}"}
+	m, err = New(opts)
+	require.Nil(t, err)
+	require.EqualValues(t, MaxInlineRangeSize, m.EstimateCount())
+}
+
+func TestMutatorInlineAlternation(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{(dev|prod|staging)}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"test"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	require.EqualValues(t, 3, m.EstimateCount())
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	results := strings.Fields(buff.String())
+	require.ElementsMatch(t, []string{"dev-api.scanme.sh", "prod-api.scanme.sh", "staging-api.scanme.sh"}, results)
+}
+
+func TestMutatorOptionalVars(t *testing.T) {
+	testcases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"{{word?}}-{{sub}}.{{suffix}}", []string{"api.scanme.sh", "dev-api.scanme.sh"}},
+		{"{{sub}}-{{word?}}-{{number}}.{{suffix}}", []string{"api-1.scanme.sh", "api-dev-1.scanme.sh"}},
+		{"{{sub}}.{{word?}}-{{number}}.{{suffix}}", []string{"api.1.scanme.sh", "api.dev-1.scanme.sh"}},
+		{"{{sub}}-{{word?}}.{{suffix}}", []string{"api.scanme.sh", "api-dev.scanme.sh"}},
+		{"{{sub}}.{{word?}}.{{suffix}}", []string{"api.scanme.sh", "api.dev.scanme.sh"}},
+	}
+	for _, tc := range testcases {
+		m, err := New(&Options{
+			Domains:  []string{"api.scanme.sh"},
+			Patterns: []string{tc.pattern},
+			Payloads: map[string][]string{"word": {"dev"}, "number": {"1"}},
+			MaxSize:  math.MaxInt,
+		})
+		require.Nil(t, err)
+		var buff bytes.Buffer
+		require.Nil(t, m.ExecuteWithWriter(&buff))
+		require.ElementsMatch(t, tc.expected, strings.Fields(buff.String()), tc.pattern)
+	}
+}
+
+func BenchmarkReplace(b *testing.B) {
+	template := "{{word}}-{{sub}}.{{suffix}}"
+	values := map[string]interface{}{"word": "dev", "sub": "api", "suffix": "scanme.sh"}
+	b.Run("on-the-fly", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = Replace(template, values)
+		}
+	})
+	b.Run("precompiled", func(b *testing.B) {
+		tpl, err := NewTemplate(template, ParenthesisOpen, ParenthesisClose)
+		require.Nil(b, err)
+		for i := 0; i < b.N; i++ {
+			_ = ReplaceTemplate(tpl, values)
+		}
+	})
+}
+
+func BenchmarkMutatorExecute(b *testing.B) {
+	domains := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		domains = append(domains, fmt.Sprintf("api%d.dev.scanme.sh", i))
+	}
+	m, err := New(&Options{
+		Domains:  domains,
+		Patterns: DefaultConfig.Patterns,
+		Payloads: DefaultConfig.Payloads,
+		MaxSize:  math.MaxInt,
+	})
+	require.Nil(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := m.ExecuteWithCallback(context.Background(), func(string) error { return nil }); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMutatorEstimateCountOverflow(t *testing.T) {
+	payloads := map[string][]string{}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		for i := 0; i < 10000; i++ {
+			payloads[key] = append(payloads[key], fmt.Sprintf("%v%v", key, i))
+		}
+	}
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{a}}-{{b}}-{{c}}-{{d}}-{{e}}.{{root}}"},
+		Payloads: payloads,
+	})
+	require.Nil(t, err)
+	// true count is 10^20 which exceeds int64 range
+	require.Equal(t, int64(math.MaxInt64), m.EstimateCount())
+	require.Equal(t, math.MaxInt, m.PayloadCount())
+	require.Equal(t, maxDedupeBytes, dedupeSize(m.EstimateCount(), m.maxkeyLenInBytes))
+}
+
+func TestMutatorDuplicateTokens(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{sub}}-{{word}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"a", "pi", "api", "scan", "dev"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	// words that are only part of a token (ex: a, pi) are no longer skipped
+	require.ElementsMatch(t, []string{"api-a.scanme.sh", "api-pi.scanme.sh", "api-scan.scanme.sh", "api-dev.scanme.sh"}, strings.Fields(buff.String()))
+
+	opts.AllowDuplicateTokens = true
+	m, err = New(opts)
+	require.Nil(t, err)
+	buff.Reset()
+	require.Nil(t, m.ExecuteWithWriter(&buff))
+	require.Contains(t, strings.Fields(buff.String()), "api-api.scanme.sh")
+}
+
+func TestMutatorEmptyPayloads(t *testing.T) {
+	m, err := New(&Options{
+		Domains:      []string{"api.scanme.sh"},
+		Patterns:     []string{"{{word}}-{{sub}}.{{suffix}}", "{{sub}}-{{env}}.{{suffix}}"},
+		Payloads:     map[string][]string{"word": {"dev"}, "env": {"prod"}},
+		ExcludeWords: []string{"dev"},
+		MaxSize:      math.MaxInt,
+	})
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	require.Equal(t, []string{"api-prod.scanme.sh"}, strings.Fields(buff.String()))
+	require.Equal(t, 1, stats.SkippedPatterns)
+}
+
+func TestMutatorExecuteToSlice(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh", "api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod", "stage"}},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"dev-api.scanme.sh", "prod-api.scanme.sh", "stage-api.scanme.sh"}, results)
+
+	opts.Limit = 2
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err = m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+
+	opts.Limit = 0
+	opts.MaxSize = len("dev-api.scanme.sh\n")
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err = m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+}
+
+func TestMutatorExecuteToSliceDefaults(t *testing.T) {
+	// MaxSize is not set i.e output size is not limited
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod"}},
+	})
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"dev-api.scanme.sh", "prod-api.scanme.sh"}, results)
+
+	// writer output is still limited to MaxSize i.e nothing is written
+	var buff bytes.Buffer
+	err = m.ExecuteWithWriter(&buff)
+	require.Nil(t, err)
+	require.Empty(t, buff.String())
+}
+
+func TestMutatorAddDomains(t *testing.T) {
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+		Enrich:   true,
+		MaxSize:  math.MaxInt,
+	})
+	require.Nil(t, err)
+	err = m.AddDomains("cdn.scanme.sh", "*.*.scanme.sh")
+	require.NotNil(t, err)
+	require.Len(t, m.Inputs, 2)
+	require.Len(t, m.InputErrors(), 1)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Contains(t, results, "dev-cdn.scanme.sh")
+	// words of added domains are used for enrichment
+	require.Contains(t, results, "cdn-api.scanme.sh")
+}
+
+func TestMutatorOutputWrap(t *testing.T) {
+	m, err := New(&Options{
+		Domains:      []string{"api.scanme.sh", "api.scanme.sh"},
+		Patterns:     []string{"{{word}}-{{sub}}.{{suffix}}", "{{word}}{{sub}}.{{suffix}}"},
+		Payloads:     map[string][]string{"word": {"dev", "-"}},
+		OutputPrefix: "https://",
+		OutputSuffix: ":8443",
+		MaxSize:      math.MaxInt,
+	})
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	// results are deduped on bare host and hosts starting with hyphen are dropped
+	require.ElementsMatch(t, []string{"https://dev-api.scanme.sh:8443", "https://devapi.scanme.sh:8443"}, strings.Fields(buff.String()))
+	require.Equal(t, 2, stats.Filtered)
+}
+
+func TestMutatorFilterFunc(t *testing.T) {
+	known := map[string]struct{}{"dev-api.scanme.sh": {}, "prod-api.scanme.sh": {}}
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod", "stage", "qa"}},
+		Limit:    2,
+		FilterFunc: func(subdomain string) bool {
+			_, ok := known[subdomain]
+			return !ok
+		},
+		MaxSize: math.MaxInt,
+	})
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	// dropped results are not counted towards limit
+	require.ElementsMatch(t, []string{"stage-api.scanme.sh", "qa-api.scanme.sh"}, strings.Fields(buff.String()))
+	require.Equal(t, 2, stats.Filtered)
+}
+
+func TestMutatorTransformFunc(t *testing.T) {
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod", "stage", "qa"}},
+		Limit:    2,
+		TransformFunc: func(subdomain string) string {
+			if strings.HasPrefix(subdomain, "dev-") {
+				// dropped and not counted towards limit
+				return ""
+			}
+			return subdomain + ".corp"
+		},
+		MaxSize: math.MaxInt,
+	})
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	for _, v := range results {
+		require.True(t, strings.HasSuffix(v, ".scanme.sh.corp"), v)
+		require.False(t, strings.HasPrefix(v, "dev-"), v)
+	}
+}
+
+func TestMutatorDedupeBackends(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh", "api.scanme.sh", "API.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}", "{{sub}}-{{word}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod"}},
+		MaxSize:  math.MaxInt,
+	}
+	expected := []string{"dev-api.scanme.sh", "prod-api.scanme.sh", "api-dev.scanme.sh", "api-prod.scanme.sh"}
+	for _, configure := range []func(){
+		func() { opts.DedupeDisk = true },
+		func() { opts.DedupeDisk = false; opts.DedupeMemoryLimit = 1 },
+		func() { opts.DedupeMemoryLimit = 0; opts.DedupeBloom = true },
+	} {
+		configure()
+		m, err := New(opts)
+		require.Nil(t, err)
+		results, err := m.ExecuteToSlice(context.Background())
+		require.Nil(t, err)
+		require.ElementsMatch(t, expected, results)
+	}
+}
+
+func TestBloomBackendOverCapacity(t *testing.T) {
+	// filter is sized for bloomMinElements but 6x more elements are added
+	backend := newBloomBackend(1000, bloomFalsePositiveRate)
+	total := 6 * bloomMinElements
+	dropped := 0
+	for i := 0; i < total; i++ {
+		if !backend.Upsert(fmt.Sprintf("host%v.scanme.sh", i)) {
+			dropped++
+		}
+	}
+	require.Greater(t, len(backend.filters), 1)
+	require.Less(t, float64(dropped)/float64(total), 2*bloomFalsePositiveRate)
+	// duplicates are detected in all filters
+	for _, i := range []int{0, bloomMinElements + 1, total - 1} {
+		require.False(t, backend.Upsert(fmt.Sprintf("host%v.scanme.sh", i)))
+	}
+}
+
+func TestBloomBackendSaturatedEstimate(t *testing.T) {
+	// saturated estimate does not allocate max size filter up front
+	backend := newBloomBackend(math.MaxInt64, bloomFalsePositiveRate)
+	require.EqualValues(t, bloomMaxInitialElements, backend.filters[0].capacity)
+	require.Less(t, backend.filters[0].size, uint64(bloomMaxBits))
+
+	// capacity of clamped filter is reduced to honor false positive rate
+	size, capacity := bloomSize(math.MaxInt64, bloomFalsePositiveRate)
+	require.EqualValues(t, bloomMaxBits, size)
+	require.Less(t, capacity, int64(6e8))
+	require.Greater(t, capacity, int64(5e8))
+	fpRate := math.Pow(1-math.Exp(-10*float64(capacity)/float64(size)), 10)
+	require.Less(t, fpRate, 1.1*bloomFalsePositiveRate)
+}
+
+func TestMutatorShuffle(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{sub}}-{{number:00-99}}.{{suffix}}"},
+		MaxSize:  math.MaxInt,
+		Seed:     7,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	ordered, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+
+	for _, window := range []int{0, 10} {
+		opts.Shuffle = true
+		opts.ShuffleWindow = window
+		m, err = New(opts)
+		require.Nil(t, err)
+		shuffled, err := m.ExecuteToSlice(context.Background())
+		require.Nil(t, err)
+		require.ElementsMatch(t, ordered, shuffled)
+		require.NotEqual(t, ordered, shuffled)
+
+		// same seed produces same order
+		m, err = New(opts)
+		require.Nil(t, err)
+		again, err := m.ExecuteToSlice(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, shuffled, again)
+	}
+}
+
+func TestMutatorKnownHosts(t *testing.T) {
+	knownHostsFile := filepath.Join(t.TempDir(), "known.txt")
+	require.Nil(t, os.WriteFile(knownHostsFile, []byte("dev-api.scanme.sh\n\nPROD-API.scanme.sh.\n"), 0644))
+	m, err := New(&Options{
+		Domains:        []string{"api.scanme.sh"},
+		Patterns:       []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads:       map[string][]string{"word": {"dev", "prod", "stage"}},
+		KnownHostsFile: knownHostsFile,
+		MaxSize:        math.MaxInt,
+	})
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"stage-api.scanme.sh"}, results)
+
+	_, err = New(&Options{Domains: []string{"api.scanme.sh"}, KnownHostsFile: filepath.Join(t.TempDir(), "missing.txt")})
+	require.NotNil(t, err)
+}
+
+func TestMutatorGzipWriter(t *testing.T) {
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod"}},
+		MaxSize:  math.MaxInt,
+	})
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	writer := NewGzipWriter(&buff)
+	require.Nil(t, m.ExecuteWithWriter(writer))
+	require.Nil(t, writer.Close())
+
+	reader, err := gzip.NewReader(&buff)
+	require.Nil(t, err)
+	bin, err := io.ReadAll(reader)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"dev-api.scanme.sh", "prod-api.scanme.sh"}, strings.Fields(string(bin)))
+}
+
+func TestMutatorClose(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	m.Close()
+	require.Nil(t, m.Inputs)
+	require.Nil(t, m.templates)
+	require.NotPanics(t, m.Close)
+
+	// options can be reused after Close
+	require.Equal(t, map[string][]string{"word": {"dev"}}, opts.Payloads)
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, []string{"dev-api.scanme.sh"}, results)
+}
+
+func TestMutatorReset(t *testing.T) {
+	m, err := New(&Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev", "prod", "stage", "qa"}},
+		Shuffle:  true,
+		Seed:     7,
+		MaxSize:  math.MaxInt,
+	})
+	require.Nil(t, err)
+	var first bytes.Buffer
+	stats, err := m.Run(context.Background(), &first)
+	require.Nil(t, err)
+	require.Equal(t, 4, stats.Written)
+	require.Equal(t, 4, m.PayloadCount())
+
+	m.Reset()
+	require.Equal(t, "0.0000s", m.Time())
+	var second bytes.Buffer
+	stats, err = m.Run(context.Background(), &second)
+	require.Nil(t, err)
+	require.Equal(t, 4, stats.Generated)
+	require.Equal(t, first.String(), second.String())
+}
+
+func TestMutatorCoverage(t *testing.T) {
+	for _, interleave := range []bool{false, true} {
+		m, err := New(&Options{
+			Domains:        []string{"api.scanme.sh", "api.dev.scanme.sh"},
+			Patterns:       []string{"{{word}}-{{sub}}.{{suffix}}", "{{sub}}-{{sub1}}.{{root}}", "{{sub}}.{{root}}"},
+			Payloads:       map[string][]string{"word": {"dev", "prod"}},
+			CoverageReport: true,
+			Interleave:     interleave,
+			MaxSize:        math.MaxInt,
+		})
+		require.Nil(t, err)
+		_, err = m.ExecuteToSlice(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, map[string]map[string]int{
+			"api.scanme.sh": {
+				"{{word}}-{{sub}}.{{suffix}}": 2,
+				"{{sub}}.{{root}}":            1,
+			},
+			"api.dev.scanme.sh": {
+				// dev is skipped since it is already present in suffix
+				"{{word}}-{{sub}}.{{suffix}}": 1,
+				"{{sub}}-{{sub1}}.{{root}}":   1,
+				// duplicate of api.scanme.sh
+				"{{sub}}.{{root}}": 0,
+			},
+		}, m.Coverage())
+	}
+}
+
+func TestMutatorUnknownVars(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.scanme.sh"},
+		Patterns: []string{"{{servce}}-{{sub}}.{{suffix}}", "{{word:0}}-{{sub2}}.{{root}}", "{{(dev|prod)}}-{{word?}}.{{root}}"},
+		Payloads: map[string][]string{"word": {"dev"}, "service": {"api"}},
+	}
+	_, err := New(opts)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "servce")
+	require.NotContains(t, err.Error(), "sub2")
+
+	opts.Patterns = opts.Patterns[1:]
+	_, err = New(opts)
+	require.Nil(t, err)
+}
+
+func TestMutatorGetters(t *testing.T) {
+	m, err := New(&Options{
+		Domains:      []string{"api.scanme.sh"},
+		Patterns:     []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads:     map[string][]string{"word": {"dev", "dev", "prod"}},
+		ExcludeWords: []string{"prod"},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"{{word}}-{{sub}}.{{suffix}}"}, m.Patterns())
+	payloads := m.Payloads()
+	require.Equal(t, map[string][]string{"word": {"dev"}}, payloads)
+
+	// returned values are copies
+	payloads["word"][0] = "changed"
+	m.Patterns()[0] = "changed"
+	require.Equal(t, []string{"dev"}, m.Payloads()["word"])
+	require.Equal(t, []string{"{{word}}-{{sub}}.{{suffix}}"}, m.Patterns())
+}
+
+// collectVariants returns distinct variants sent to callback by generator
+func collectVariants(t *testing.T, generator func(callback func(variant string) error) error) []string {
+	var variants []string
+	seen := map[string]struct{}{}
+	require.Nil(t, generator(func(variant string) error {
+		if _, ok := seen[variant]; !ok {
+			seen[variant] = struct{}{}
+			variants = append(variants, variant)
+		}
+		return nil
+	}))
+	return variants
+}
+
+func TestMutatorTyposquat(t *testing.T) {
+	variants := collectVariants(t, func(callback func(string) error) error {
+		return homoglyphVariants("mail", DefaultHomoglyphs, 1, callback)
+	})
+	require.ElementsMatch(t, []string{"rnail", "ma1l", "mall", "mai1", "maii"}, variants)
+	require.EqualValues(t, 5, countHomoglyphVariants("mail", DefaultHomoglyphs, 1))
+
+	opts := &Options{
+		Domains:           []string{"foo.example.com"},
+		Patterns:          []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads:          map[string][]string{"word": {"dev"}},
+		Typosquat:         true,
+		TyposquatMaxEdits: 2,
+		Homoglyphs:        map[string][]string{"o": {"0"}},
+		MaxSize:           math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	// public suffix (com) is never changed
+	require.ElementsMatch(t, []string{"dev-foo.example.com", "f0o.example.com", "fo0.example.com", "f00.example.com"}, results)
+	require.EqualValues(t, 4, m.EstimateCount())
+
+	// variants are limited like permutations
+	opts.Limit = 2
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err = m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+}
+
+func TestMutatorTypoGen(t *testing.T) {
+	typos := func(label string, maxEdits int) []string {
+		return collectVariants(t, func(callback func(string) error) error {
+			return typoVariants(label, maxEdits, callback)
+		})
+	}
+	variants := typos("ab", 1)
+	// 2 deletions + 1 transposition + 70 substitutions + 107 insertions
+	require.Len(t, variants, 180)
+	for _, v := range []string{"a", "b", "ba", "xb", "a9", "a-b", "aab", "abb", "abz", "0ab"} {
+		require.Contains(t, variants, v)
+	}
+	for _, v := range []string{"ab", "-b", "a-", "-ab", "ab-"} {
+		require.NotContains(t, variants, v)
+	}
+	require.Contains(t, typos("ab", 2), "bac")
+	// upper bound of variants including duplicates i.e 111 insertions + 2 deletions + 72 substitutions + 1 transposition
+	require.EqualValues(t, 186, countTypoVariants(2, 1))
+
+	opts := &Options{
+		Domains:  []string{"ab.example.com"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+		MaxSize:  math.MaxInt,
+		TypoGen:  true,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	var buff bytes.Buffer
+	stats, err := m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	results := strings.Fields(buff.String())
+	require.Contains(t, results, "dev-ab.example.com")
+	require.Contains(t, results, "ba.example.com")
+	require.Contains(t, results, "ab.exmaple.com")
+	require.NotContains(t, results, "ab.example.cmo")
+	require.Len(t, results, 1+180+len(typos("example", 1)))
+	require.EqualValues(t, 1+countTypoVariants(2, 1)+countTypoVariants(7, 1), m.EstimateCount())
+	require.LessOrEqual(t, int64(stats.Generated), m.EstimateCount())
+
+	// variants are generated on the fly and generation stops at limit
+	opts.Domains = []string{"mailserver.example.com"}
+	opts.TypoMaxEdits = MaxTypoEdits
+	opts.Limit = 10
+	m, err = New(opts)
+	require.Nil(t, err)
+	stats, err = m.Run(context.Background(), &buff)
+	require.Nil(t, err)
+	require.Equal(t, 10, stats.Written)
+	require.Less(t, stats.Generated, 100)
+
+	opts.TypoMaxEdits = MaxTypoEdits + 1
+	_, err = New(opts)
+	require.NotNil(t, err)
+}
+
+func TestMutatorTLDSwap(t *testing.T) {
+	opts := &Options{
+		Domains:  []string{"api.example.com", "api.example.co.uk", "internal"},
+		Patterns: []string{"{{word}}-{{sub}}.{{suffix}}"},
+		Payloads: map[string][]string{"word": {"dev"}},
+		TLDSwap:  true,
+		SwapTLDs: []string{"com", "net", ".io", "co.uk"},
+		MaxSize:  math.MaxInt,
+	}
+	m, err := New(opts)
+	require.Nil(t, err)
+	results, err := m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	// distinct tlds are never collapsed but same result of different inputs is deduped
+	require.ElementsMatch(t, []string{
+		"dev-api.example.com", "dev-api.example.co.uk",
+		"api.example.net", "api.example.io", "api.example.co.uk", "api.example.com",
+	}, results)
+	require.EqualValues(t, 8, m.EstimateCount())
+
+	opts.SwapTLDs = nil
+	m, err = New(opts)
+	require.Nil(t, err)
+	results, err = m.ExecuteToSlice(context.Background())
+	require.Nil(t, err)
+	require.Contains(t, results, "api.example.xyz")
+}
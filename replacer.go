@@ -2,6 +2,8 @@ package alterx
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/projectdiscovery/fasttemplate"
 )
@@ -17,11 +19,59 @@ const (
 
 // Replace replaces placeholders in template with values on the fly.
 func Replace(template string, values map[string]interface{}) string {
+	return ReplaceWithDelims(template, ParenthesisOpen, ParenthesisClose, values)
+}
+
+// ReplaceWithDelims replaces placeholders marked by given open/close delimiters in template with values on the fly.
+func ReplaceWithDelims(template, open, close string, values map[string]interface{}) string {
+	valuesMap := stringValues(values)
+	replaced := fasttemplate.ExecuteStringStd(template, open, close, valuesMap)
+	final := fasttemplate.ExecuteStringStd(replaced, General, General, valuesMap)
+	return final
+}
+
+// Template is a precompiled template whose placeholders can be replaced
+// repeatedly without parsing template every time
+type Template struct {
+	compiled *fasttemplate.Template
+	open     string
+	close    string
+}
+
+// NewTemplate compiles template with placeholders marked by given open/close delimiters
+func NewTemplate(template, open, close string) (*Template, error) {
+	compiled, err := fasttemplate.NewTemplate(template, open, close)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{compiled: compiled, open: open, close: close}, nil
+}
+
+// ReplaceTemplate replaces placeholders in precompiled template with values.
+// unlike Replace values are looked up directly without copying them
+func ReplaceTemplate(template *Template, values map[string]interface{}) string {
+	replaced := template.compiled.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		v, ok := values[tag]
+		if !ok {
+			// keep unknown placeholders as is
+			return io.WriteString(w, template.open+tag+template.close)
+		}
+		if value, ok := v.(string); ok {
+			return io.WriteString(w, value)
+		}
+		return io.WriteString(w, fmt.Sprint(v))
+	})
+	if !strings.Contains(replaced, General) {
+		return replaced
+	}
+	return fasttemplate.ExecuteStringStd(replaced, General, General, stringValues(values))
+}
+
+// stringValues returns copy of values with all values converted to string
+func stringValues(values map[string]interface{}) map[string]interface{} {
 	valuesMap := make(map[string]interface{}, len(values))
 	for k, v := range values {
 		valuesMap[k] = fmt.Sprint(v)
 	}
-	replaced := fasttemplate.ExecuteStringStd(template, ParenthesisOpen, ParenthesisClose, valuesMap)
-	final := fasttemplate.ExecuteStringStd(replaced, General, General, valuesMap)
-	return final
+	return valuesMap
 }
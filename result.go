@@ -0,0 +1,42 @@
+package alterx
+
+import "time"
+
+const (
+	// SourceDefault is the source of results generated from patterns and payloads
+	SourceDefault = "default"
+	// SourceTyposquat is the source of homoglyph variants of inputs (see Options.Typosquat)
+	SourceTyposquat = "typosquat"
+	// SourceTypo is the source of character typo variants of inputs (see Options.TypoGen)
+	SourceTypo = "typo"
+	// SourceTLDSwap is the source of inputs with swapped public suffix (see Options.TLDSwap)
+	SourceTLDSwap = "tldswap"
+)
+
+// Result contains a generated subdomain along with its provenance
+type Result struct {
+	Subdomain string `json:"subdomain"`
+	Pattern   string `json:"pattern"`
+	Source    string `json:"source"`
+}
+
+// RunStats contains statistics of a single run
+type RunStats struct {
+	// Generated is number of permutations generated (including duplicates)
+	Generated int `json:"generated"`
+	// Duplicates is number of permutations dropped as duplicates
+	Duplicates int `json:"duplicates"`
+	// Filtered is number of permutations dropped by output filters
+	Filtered int `json:"filtered"`
+	// Written is number of permutations written to output
+	Written int `json:"written"`
+	// SkippedPatterns is number of (input, pattern) pairs skipped due to missing variables
+	SkippedPatterns int `json:"skipped_patterns"`
+	// CappedInputs is number of inputs that reached MaxPerInput
+	CappedInputs int `json:"capped_inputs"`
+	// CappedValues is number of permutations of capped inputs skipped due to MaxPerInput. since skipped
+	// permutations are never generated it is estimated like EstimateCount (including duplicates)
+	CappedValues int64 `json:"capped_values"`
+	// TimeTaken is time taken to generate permutations
+	TimeTaken time.Duration `json:"time_taken"`
+}
@@ -0,0 +1,378 @@
+package alterx
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	sliceutil "github.com/projectdiscovery/utils/slice"
+)
+
+// DefaultHomoglyphs contains visually confusable character sequences
+// used by typosquat generation when Options.Homoglyphs is not set
+var DefaultHomoglyphs = map[string][]string{
+	"0":  {"o"},
+	"1":  {"l", "i"},
+	"3":  {"e"},
+	"5":  {"s"},
+	"cl": {"d"},
+	"d":  {"cl"},
+	"e":  {"3"},
+	"g":  {"q"},
+	"i":  {"1", "l"},
+	"l":  {"1", "i"},
+	"m":  {"rn"},
+	"o":  {"0"},
+	"q":  {"g"},
+	"rn": {"m"},
+	"s":  {"5"},
+	"vv": {"w"},
+	"w":  {"vv"},
+}
+
+// DefaultSwapTLDs contains common public suffixes used by TLD swap
+// generation when Options.SwapTLDs is not set
+var DefaultSwapTLDs = []string{"com", "net", "org", "io", "co", "info", "biz", "us", "dev", "app", "xyz", "co.uk"}
+
+// defaultTyposquatMaxEdits is default value of Options.TyposquatMaxEdits
+const defaultTyposquatMaxEdits = 1
+
+// defaultTypoMaxEdits is default value of Options.TypoMaxEdits
+const defaultTypoMaxEdits = 1
+
+// MaxTypoEdits is max supported value of Options.TypoMaxEdits. no of typo variants
+// grows by ~75x length of label with each edit (ex: ~800k variants of a 10 char label with 2 edits)
+const MaxTypoEdits = 2
+
+// typoAlphabet contains characters used for insertion and substitution typos
+const typoAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789-"
+
+// generateTyposquats emits homoglyph variants of every label of all inputs (excluding public suffix)
+func (m *Mutator) generateTyposquats(ctx context.Context, emit func(res Result) (bool, error)) error {
+	glyphs := m.homoglyphs()
+	return m.generateLabelVariants(ctx, emit, SourceTyposquat, func(label string, callback func(variant string) error) error {
+		return homoglyphVariants(label, glyphs, m.Options.TyposquatMaxEdits, callback)
+	})
+}
+
+// generateTypos emits insertion, deletion, substitution and transposition variants
+// of every label of all inputs (excluding public suffix)
+func (m *Mutator) generateTypos(ctx context.Context, emit func(res Result) (bool, error)) error {
+	return m.generateLabelVariants(ctx, emit, SourceTypo, func(label string, callback func(variant string) error) error {
+		return typoVariants(label, m.Options.TypoMaxEdits, callback)
+	})
+}
+
+// homoglyphs returns homoglyph map used by typosquat generation
+func (m *Mutator) homoglyphs() map[string][]string {
+	if m.Options.Homoglyphs == nil {
+		return DefaultHomoglyphs
+	}
+	return m.Options.Homoglyphs
+}
+
+// swapTLDs returns normalized and distinct tlds used by tld swap generation
+func (m *Mutator) swapTLDs() []string {
+	tlds := m.Options.SwapTLDs
+	if len(tlds) == 0 {
+		tlds = DefaultSwapTLDs
+	}
+	var normalized []string
+	for _, tld := range tlds {
+		if tld = normalizeHostname(strings.TrimPrefix(strings.TrimSpace(tld), ".")); tld != "" {
+			normalized = append(normalized, tld)
+		}
+	}
+	return sliceutil.Dedupe(normalized)
+}
+
+// estimateVariants estimates no of results generated by Typosquat, TypoGen and TLDSwap
+// (including duplicates). estimate saturates at math.MaxInt64 instead of overflowing
+func (m *Mutator) estimateVariants() int64 {
+	if !m.Options.Typosquat && !m.Options.TypoGen && !m.Options.TLDSwap {
+		return 0
+	}
+	var counter int64
+	glyphs := m.homoglyphs()
+	tlds := m.swapTLDs()
+	for _, input := range m.Inputs {
+		labels, editable := inputLabels(input)
+		hostname := input.hostname()
+		for index := 0; index < editable; index++ {
+			if m.Options.Typosquat {
+				counter = saturatingAdd(counter, countHomoglyphVariants(labels[index], glyphs, m.Options.TyposquatMaxEdits))
+			}
+			if m.Options.TypoGen {
+				counter = saturatingAdd(counter, countTypoVariants(len(labels[index]), m.Options.TypoMaxEdits))
+			}
+		}
+		keyLen := len(hostname)
+		if m.Options.TypoGen {
+			// every insertion adds one char
+			keyLen += m.Options.TypoMaxEdits
+		}
+		if m.Options.TLDSwap && editable > 0 && editable < len(labels) {
+			prefix := strings.Join(labels[:editable], ".")
+			for _, tld := range tlds {
+				if tld != strings.Join(labels[editable:], ".") {
+					counter = saturatingAdd(counter, 1)
+				}
+				if len(prefix)+1+len(tld) > keyLen {
+					keyLen = len(prefix) + 1 + len(tld)
+				}
+			}
+		}
+		if m.maxkeyLenInBytes < keyLen {
+			m.maxkeyLenInBytes = keyLen
+		}
+	}
+	return counter
+}
+
+// generateTLDSwaps emits results by replacing public suffix (tld/etld) of all inputs
+// with each of swap tlds (ex: api.example.com => api.example.net, api.example.io)
+func (m *Mutator) generateTLDSwaps(ctx context.Context, emit func(res Result) (bool, error)) error {
+	tlds := m.swapTLDs()
+	for _, input := range m.Inputs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		labels, editable := inputLabels(input)
+		if editable == 0 || editable == len(labels) {
+			// public suffix or rootless input
+			continue
+		}
+		current := strings.Join(labels[editable:], ".")
+		prefix := strings.Join(labels[:editable], ".")
+		for _, tld := range tlds {
+			if tld == current {
+				continue
+			}
+			if _, err := emit(Result{Subdomain: prefix + "." + tld, Source: SourceTLDSwap}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// generateLabelVariants replaces one label of each input at a time with its variants and emits results.
+// variants of a label are generated on the fly and sent to callback one at a time
+func (m *Mutator) generateLabelVariants(ctx context.Context, emit func(res Result) (bool, error), source string, variantsOf func(label string, callback func(variant string) error) error) error {
+	for _, input := range m.Inputs {
+		labels, editable := inputLabels(input)
+		for index := 0; index < editable; index++ {
+			err := variantsOf(labels[index], func(variant string) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				_, err := emit(Result{Subdomain: replaceLabel(labels, index, variant), Source: source})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inputLabels returns labels of input hostname along with no of leading labels
+// that are not part of public suffix (ex: api.scanme.co.uk => [api scanme co uk], 2)
+func inputLabels(input *Input) ([]string, int) {
+	labels := strings.Split(input.hostname(), ".")
+	suffixLabels := 0
+	switch {
+	case input.ETLD != "":
+		suffixLabels = strings.Count(input.ETLD, ".") + 1
+	case input.TLD != "":
+		suffixLabels = 1
+	}
+	if suffixLabels > len(labels) {
+		return labels, 0
+	}
+	return labels, len(labels) - suffixLabels
+}
+
+// replaceLabel returns hostname with label at given index replaced by value
+func replaceLabel(labels []string, index int, value string) string {
+	replaced := make([]string, len(labels))
+	copy(replaced, labels)
+	replaced[index] = value
+	return strings.Join(replaced, ".")
+}
+
+// sortedHomoglyphKeys returns non-empty keys of glyphs with longer sequences first
+// so that variants are generated in deterministic order
+func sortedHomoglyphKeys(glyphs map[string][]string) []string {
+	keys := make([]string, 0, len(glyphs))
+	for k := range glyphs {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// homoglyphVariants sends all variants of label with 1 to maxEdits non-overlapping
+// homoglyph substitutions to callback (ex: mail with 1 => rnail, mai1, maii).
+// variants are generated on the fly and it stops as soon as callback returns an error
+func homoglyphVariants(label string, glyphs map[string][]string, maxEdits int, callback func(variant string) error) error {
+	keys := sortedHomoglyphKeys(glyphs)
+	var walk func(pos int, prefix string, edits int) error
+	walk = func(pos int, prefix string, edits int) error {
+		if pos == len(label) {
+			if edits > 0 && prefix != label {
+				return callback(prefix)
+			}
+			return nil
+		}
+		if err := walk(pos+1, prefix+label[pos:pos+1], edits); err != nil {
+			return err
+		}
+		if edits >= maxEdits {
+			return nil
+		}
+		for _, k := range keys {
+			if !strings.HasPrefix(label[pos:], k) {
+				continue
+			}
+			for _, replacement := range glyphs[k] {
+				if err := walk(pos+len(k), prefix+replacement, edits+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(0, "", 0)
+}
+
+// countHomoglyphVariants returns no of variants generated by homoglyphVariants
+// without generating them (may include a few variants equal to label)
+func countHomoglyphVariants(label string, glyphs map[string][]string, maxEdits int) int64 {
+	keys := sortedHomoglyphKeys(glyphs)
+	// memo[pos][edits] is no of variants of label[pos:] after edits substitutions (-1 = unknown)
+	memo := make([][]int64, len(label)+1)
+	for i := range memo {
+		memo[i] = make([]int64, maxEdits+1)
+		for j := range memo[i] {
+			memo[i][j] = -1
+		}
+	}
+	var count func(pos, edits int) int64
+	count = func(pos, edits int) int64 {
+		if pos == len(label) {
+			if edits > 0 {
+				return 1
+			}
+			return 0
+		}
+		if memo[pos][edits] >= 0 {
+			return memo[pos][edits]
+		}
+		total := count(pos+1, edits)
+		if edits < maxEdits {
+			for _, k := range keys {
+				if strings.HasPrefix(label[pos:], k) {
+					total = saturatingAdd(total, saturatingMul(int64(len(glyphs[k])), count(pos+len(k), edits+1)))
+				}
+			}
+		}
+		memo[pos][edits] = total
+		return total
+	}
+	return count(0, 0)
+}
+
+// typoVariants sends all variants of label within maxEdits character operations i.e insertion,
+// deletion, substitution and adjacent transposition to callback (ex: mail => ail, maail, nail, amil).
+// variants are generated on the fly (without dedupe) and variants that are not valid labels are skipped.
+// it stops as soon as callback returns an error
+func typoVariants(label string, maxEdits int, callback func(variant string) error) error {
+	var walk func(current string, edits int) error
+	walk = func(current string, edits int) error {
+		return singleEdits(current, func(variant string) error {
+			if variant == "" || variant[0] == '-' || variant[len(variant)-1] == '-' {
+				return nil
+			}
+			if variant != label {
+				if err := callback(variant); err != nil {
+					return err
+				}
+			}
+			if edits+1 < maxEdits {
+				return walk(variant, edits+1)
+			}
+			return nil
+		})
+	}
+	if maxEdits <= 0 {
+		return nil
+	}
+	return walk(label, 0)
+}
+
+// countTypoVariants returns upper bound of no of variants generated by typoVariants
+// for a label of given length without generating them
+func countTypoVariants(length, maxEdits int) int64 {
+	if maxEdits <= 0 || length < 0 {
+		return 0
+	}
+	alphabet := int64(len(typoAlphabet))
+	n := int64(length)
+	insertions := alphabet * (n + 1)
+	deletions := n
+	substitutions := (alphabet - 1) * n
+	transpositions := int64(0)
+	if n > 1 {
+		transpositions = n - 1
+	}
+	total := insertions + deletions + substitutions + transpositions
+	total = saturatingAdd(total, saturatingMul(insertions, countTypoVariants(length+1, maxEdits-1)))
+	total = saturatingAdd(total, saturatingMul(deletions, countTypoVariants(length-1, maxEdits-1)))
+	total = saturatingAdd(total, saturatingMul(substitutions+transpositions, countTypoVariants(length, maxEdits-1)))
+	return total
+}
+
+// singleEdits sends all variants of label that are one character operation away
+// to callback (may contain duplicates). it stops as soon as callback returns an error
+func singleEdits(label string, callback func(variant string) error) error {
+	for i := 0; i <= len(label); i++ {
+		for j := 0; j < len(typoAlphabet); j++ {
+			// insertion
+			if err := callback(label[:i] + typoAlphabet[j:j+1] + label[i:]); err != nil {
+				return err
+			}
+		}
+		if i == len(label) {
+			break
+		}
+		// deletion
+		if err := callback(label[:i] + label[i+1:]); err != nil {
+			return err
+		}
+		for j := 0; j < len(typoAlphabet); j++ {
+			// substitution
+			if typoAlphabet[j] == label[i] {
+				continue
+			}
+			if err := callback(label[:i] + typoAlphabet[j:j+1] + label[i+1:]); err != nil {
+				return err
+			}
+		}
+		if i+1 < len(label) {
+			// transposition
+			if err := callback(label[:i] + label[i+1:i+2] + label[i:i+1] + label[i+2:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,22 +2,64 @@ package alterx
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 	"unsafe"
+
+	"github.com/projectdiscovery/utils/dedupe"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+	"golang.org/x/net/idna"
 )
 
-var varRegex = regexp.MustCompile(`\{\{([a-zA-Z0-9]+)\}\}`)
+// varNameRegex matches name of a variable present inside delimiters
+// a variable can optionally have a positional suffix (ex: {{word:0}}, {{word:1}})
+// or an inline range (ex: {{number:01-50}}) or it can be an inline alternation (ex: {{(dev|prod)}})
+// any variable can be marked optional with a trailing ? (ex: {{word?}})
+const varNameRegex = `((?:[a-zA-Z0-9]+(?::[0-9]+(?:-[0-9]+)?)?|\([a-zA-Z0-9._-]+(?:\|[a-zA-Z0-9._-]+)*\))\??)`
+
+// inlineRangeRegex matches variables with inline range (ex: number:01-50)
+var inlineRangeRegex = regexp.MustCompile(`^[a-zA-Z0-9]+:([0-9]+)-([0-9]+)$`)
+
+// inlineAlternationRegex matches inline alternation (ex: (dev|prod|staging))
+var inlineAlternationRegex = regexp.MustCompile(`^\(([a-zA-Z0-9._|-]+)\)$`)
+
+// positionalSeparator separates variable name from its position
+const positionalSeparator = ":"
+
+// optionalMarker marks a variable as optional i.e it also expands to empty string
+const optionalMarker = "?"
+
+// default delimiters i.e {{ and }}
+var defaultDelims = newDelimiters(ParenthesisOpen, ParenthesisClose)
+
+// delimiters contains markers used to denote a variable in template
+type delimiters struct {
+	open     string
+	close    string
+	varRegex *regexp.Regexp
+}
+
+// newDelimiters returns delimiters with compiled variable regex
+func newDelimiters(open, close string) *delimiters {
+	return &delimiters{
+		open:     open,
+		close:    close,
+		varRegex: regexp.MustCompile(regexp.QuoteMeta(open) + varNameRegex + regexp.QuoteMeta(close)),
+	}
+}
 
 // returns no of variables present in statement
-func getVarCount(data string) int {
-	return len(varRegex.FindAllStringSubmatch(data, -1))
+func getVarCount(data string, d *delimiters) int {
+	return len(d.varRegex.FindAllStringSubmatch(data, -1))
 }
 
 // returns names of all variables
-func getAllVars(data string) []string {
+func getAllVars(data string, d *delimiters) []string {
 	var values []string
-	for _, v := range varRegex.FindAllStringSubmatch(data, -1) {
+	for _, v := range d.varRegex.FindAllStringSubmatch(data, -1) {
 		if len(v) >= 2 {
 			values = append(values, v[1])
 		}
@@ -39,16 +81,334 @@ func getSampleMap(inputVars map[string]interface{}, payloadVars map[string][]str
 	return sMap
 }
 
+// MissingVarsError is returned when values of some variables used in pattern are not available
+type MissingVarsError struct {
+	Pattern string
+	Vars    []string // names of missing variables
+}
+
+// Error implements error interface
+func (e *MissingVarsError) Error() string {
+	return fmt.Sprintf("values of `%v` variables not found", strings.Join(e.Vars, ","))
+}
+
 // checkMissing checks if all variables/placeholders are successfully replaced
-// if not error is thrown with description
-func checkMissing(template string, data map[string]interface{}) error {
-	got := Replace(template, data)
-	if res := varRegex.FindAllString(got, -1); len(res) > 0 {
-		return fmt.Errorf("values of `%v` variables not found", strings.Join(res, ","))
+// if not *MissingVarsError is returned with names of missing variables
+func checkMissing(template string, data map[string]interface{}, d *delimiters) error {
+	got := ReplaceWithDelims(template, d.open, d.close, data)
+	var missing []string
+	for _, v := range d.varRegex.FindAllStringSubmatch(got, -1) {
+		// positional variables use values of their base variable
+		name := strings.TrimSuffix(v[1], optionalMarker)
+		if _, ok := data[name]; ok {
+			continue
+		}
+		if _, ok := data[baseVarName(name)]; !ok {
+			missing = append(missing, v[1])
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingVarsError{Pattern: template, Vars: sliceutil.Dedupe(missing)}
 	}
 	return nil
 }
 
+// getTokens returns all tokens i.e dot, dash or underscore separated parts of template excluding variables
+// (ex: api_v2-{{word}}.scanme.sh => api, v2, scanme, sh)
+func getTokens(template string, d *delimiters) map[string]struct{} {
+	tokens := map[string]struct{}{}
+	literal := d.varRegex.ReplaceAllString(template, ".")
+	for _, v := range strings.FieldsFunc(literal, func(r rune) bool { return r == '.' || r == '-' || r == '_' }) {
+		tokens[v] = struct{}{}
+	}
+	return tokens
+}
+
+// builtinVars are variables extracted from input (see Input.GetMap) apart from subN
+var builtinVars = map[string]struct{}{"sub": {}, "suffix": {}, "tld": {}, "etld": {}, "sld": {}, "root": {}}
+
+// subNRegex matches multi level sub variables (ex: sub1, sub2)
+var subNRegex = regexp.MustCompile(`^sub[0-9]+$`)
+
+// isBuiltinVar returns true if variable is extracted from input (ex: sub, root, sub1)
+func isBuiltinVar(name string) bool {
+	if _, ok := builtinVars[name]; ok {
+		return true
+	}
+	return subNRegex.MatchString(name)
+}
+
+// baseVarName returns name of variable without positional suffix and
+// optional marker (ex: word:1 => word, word? => word)
+func baseVarName(name string) string {
+	name = strings.TrimSuffix(name, optionalMarker)
+	if index := strings.Index(name, positionalSeparator); index > 0 {
+		return name[:index]
+	}
+	return name
+}
+
+// collapseOptional removes placeholder of an optional variable with empty value
+// from template along with one adjacent separator so that no dangling separator
+// is left behind (ex: {{word?}}-{{sub}}.{{root}} => {{sub}}.{{root}})
+func collapseOptional(template, name string, d *delimiters) string {
+	placeholder := d.open + name + d.close
+	for {
+		start := strings.Index(template, placeholder)
+		if start < 0 {
+			return template
+		}
+		end := start + len(placeholder)
+		switch {
+		case start > 0 && template[start-1] == '-':
+			start--
+		case (start == 0 || template[start-1] == '.') && end < len(template) && (template[end] == '-' || template[end] == '.'):
+			end++
+		}
+		template = template[:start] + template[end:]
+	}
+}
+
+// parseInlinePayload returns values of a variable defined inline in pattern
+// i.e inline range (ex: number:01-50) or inline alternation (ex: (dev|prod)).
+// it returns false if variable is not defined inline
+func parseInlinePayload(name string) ([]string, bool, error) {
+	if matches := inlineAlternationRegex.FindStringSubmatch(name); len(matches) == 2 {
+		var values []string
+		seen := map[string]struct{}{}
+		for _, v := range strings.Split(matches[1], "|") {
+			if _, ok := seen[v]; ok || v == "" {
+				continue
+			}
+			seen[v] = struct{}{}
+			values = append(values, v)
+		}
+		return values, true, nil
+	}
+	return parseInlineRange(name)
+}
+
+// MaxInlineRangeSize is max no of values an inline range can generate (ex: {{number:0-99999}})
+const MaxInlineRangeSize = 100000
+
+// parseInlineRange returns all values of a variable with inline range (ex: number:01-50)
+// values are zero-padded if start of range is zero-padded. it returns false if variable
+// is not an inline range and error if range generates more than MaxInlineRangeSize values
+func parseInlineRange(name string) ([]string, bool, error) {
+	matches := inlineRangeRegex.FindStringSubmatch(name)
+	if len(matches) != 3 {
+		return nil, false, nil
+	}
+	start, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid start of range %v got %v", name, err)
+	}
+	end, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid end of range %v got %v", name, err)
+	}
+	if start > end {
+		return nil, true, fmt.Errorf("invalid range %v: start is greater than end", name)
+	}
+	if end-start >= MaxInlineRangeSize {
+		return nil, true, fmt.Errorf("invalid range %v: range cannot have more than %v values", name, MaxInlineRangeSize)
+	}
+	format := "%d"
+	if len(matches[1]) > 1 && matches[1][0] == '0' {
+		format = "%0" + strconv.Itoa(len(matches[1])) + "d"
+	}
+	values := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		values = append(values, fmt.Sprintf(format, i))
+	}
+	return values, true, nil
+}
+
+// numberNeighbors returns at most max numbers within ±window of given numbers excluding
+// numbers themselves. neighbors are added in order of distance for all numbers at once
+// so that every number gets its closest neighbors (ex: [17 42] with 1 => 16,18,41,43).
+// zero-padding of numbers is preserved (ex: 07 with 1 => 06,08)
+func numberNeighbors(numbers []string, window, max int) []string {
+	seen := map[string]struct{}{}
+	for _, v := range numbers {
+		seen[v] = struct{}{}
+	}
+	var neighbors []string
+	for distance := 1; distance <= window; distance++ {
+		valid := false
+		for _, v := range numbers {
+			for _, offset := range []int{-distance, distance} {
+				neighbor, ok := offsetNumber(v, offset)
+				if !ok {
+					continue
+				}
+				valid = true
+				if _, ok := seen[neighbor]; ok {
+					continue
+				}
+				if len(neighbors) == max {
+					return neighbors
+				}
+				seen[neighbor] = struct{}{}
+				neighbors = append(neighbors, neighbor)
+			}
+		}
+		if !valid {
+			// no number has neighbors at this or larger distance
+			break
+		}
+	}
+	return neighbors
+}
+
+// offsetNumber returns number+offset preserving zero-padding of number.
+// it returns false if result is negative or overflows
+func offsetNumber(number string, offset int) (string, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", false
+	}
+	value := n + offset
+	if value < 0 || (offset > 0 && value < n) {
+		return "", false
+	}
+	format := "%d"
+	if len(number) > 1 && number[0] == '0' {
+		format = "%0" + strconv.Itoa(len(number)) + "d"
+	}
+	return fmt.Sprintf(format, value), true
+}
+
+// hasEqualPositional returns true if any two positional variables
+// of same base variable have same value (ex: word:0=api and word:1=api)
+func hasEqualPositional(varMap map[string]interface{}) bool {
+	seen := map[string]struct{}{}
+	for k, v := range varMap {
+		base := baseVarName(k)
+		if base == strings.TrimSuffix(k, optionalMarker) || inlineRangeRegex.MatchString(k) || v == "" {
+			continue
+		}
+		key := base + positionalSeparator + fmt.Sprint(v)
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		seen[key] = struct{}{}
+	}
+	return false
+}
+
+// maxDedupeBytes is upper bound of estimated size of dedupe data (1TB)
+const maxDedupeBytes int64 = 1 << 40
+
+// saturatingAdd returns a+b or math.MaxInt64 if sum overflows (a, b >= 0)
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// saturatingMul returns a*b or math.MaxInt64 if product overflows (a, b >= 0)
+func saturatingMul(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// clampInt converts v to int clamped to math.MaxInt (on 32-bit platforms)
+func clampInt(v int64) int {
+	if v > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(v)
+}
+
+// dedupeSize returns estimated size of dedupe data in bytes clamped to maxDedupeBytes
+func dedupeSize(count int64, keyLen int) int64 {
+	size := saturatingMul(count, int64(keyLen))
+	if size > maxDedupeBytes {
+		size = maxDedupeBytes
+	}
+	return size
+}
+
+// newDedupeBackend returns in-memory or disk backed dedupe backend
+// depending on estimated size of results in bytes and memory limit (0 = dedupe.MaxInMemoryDedupeSize)
+func newDedupeBackend(maxBytes int64, memoryLimit int) dedupe.DedupeBackend {
+	if memoryLimit <= 0 {
+		memoryLimit = dedupe.MaxInMemoryDedupeSize
+	}
+	if maxBytes <= int64(memoryLimit) {
+		return dedupe.NewMapBackend()
+	}
+	return dedupe.NewLevelDBBackend()
+}
+
+// normalizeHostname lowercases hostname and strips trailing dot (if any)
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(hostname), ".")
+}
+
+// isValidDNSName checks if hostname is a valid dns name i.e
+// labels are 1-63 chars long and only contain letters, digits and hyphens (not at start/end)
+// and total length does not exceed 253 chars
+func isValidDNSName(hostname string) bool {
+	if hostname == "" || len(hostname) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toASCIIHostname converts internationalized hostname to punycode (A-label) form
+func toASCIIHostname(hostname string) string {
+	if isASCII(hostname) {
+		return hostname
+	}
+	if ascii, err := idna.Punycode.ToASCII(hostname); err == nil {
+		return ascii
+	}
+	return hostname
+}
+
+// toUnicodeHostname converts punycode (A-label) hostname to unicode (U-label) form
+func toUnicodeHostname(hostname string) string {
+	if !strings.Contains(hostname, "xn--") {
+		return hostname
+	}
+	if unicode, err := idna.Punycode.ToUnicode(hostname); err == nil {
+		return unicode
+	}
+	return hostname
+}
+
+// isASCII returns true if data only contains ascii characters
+func isASCII(data string) bool {
+	for i := 0; i < len(data); i++ {
+		if data[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
 // TODO: add this to utils
 // unsafeToBytes converts a string to byte slice and does it with
 // zero allocations.
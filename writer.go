@@ -0,0 +1,29 @@
+package alterx
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipWriter is a gzip compressing writer which also closes underlying writer (if possible) on Close
+type GzipWriter struct {
+	*gzip.Writer
+	underlying io.Writer
+}
+
+// NewGzipWriter returns writer that gzip compresses all data written to w.
+// Close must be called to flush compressed data. it also closes w if it implements io.Closer
+func NewGzipWriter(w io.Writer) *GzipWriter {
+	return &GzipWriter{Writer: gzip.NewWriter(w), underlying: w}
+}
+
+// Close flushes and closes gzip writer and underlying writer
+func (g *GzipWriter) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		return err
+	}
+	if closer, ok := g.underlying.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}